@@ -0,0 +1,76 @@
+package recovery
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ipfs/go-cid"
+	format "github.com/ipfs/go-ipld-format"
+	"github.com/ipfs/go-merkledag"
+)
+
+// Anchor makes EncodeDAG protect Nodes left without redundancy: the root and children of Nodes left unencoded.
+// They are linked from an additional anchor Node encoded with the given Recoverability, e.g. with Reed-Solomon and
+// a single unprotected Node its Redundant Nodes are plain replicas.
+// The anchor is reported with AnchorEncoded event and is recorded in the ParentIndex as a parent of all the Nodes it
+// links, so ParentIndex.Get for the root finds it and a session using the index recovers the root directly by its CID.
+// Nodes inside subtrees skipped due to Checkpoint are not anchored.
+func Anchor(r Recoverability, pi *ParentIndex) EncodeOption {
+	return func(o *encodeOptions) {
+		o.anchor, o.pi = r, pi
+	}
+}
+
+// anchors collects Nodes left without redundancy during encoding.
+type anchors struct {
+	pi *ParentIndex
+	ls []*format.Link
+	l  sync.Mutex
+}
+
+func (a *anchors) Add(ls ...*format.Link) {
+	a.l.Lock()
+	defer a.l.Unlock()
+
+	for _, l := range ls {
+		a.ls = append(a.ls, &format.Link{Cid: l.Cid, Size: l.Size})
+	}
+}
+
+// anchor encodes all the collected Nodes under one anchor Node.
+func (de *dagEncoder) anchor(ctx context.Context, id cid.Cid, root format.Node, r Recoverability) error {
+	s, err := root.Size()
+	if err != nil {
+		return err
+	}
+	de.anc.Add(&format.Link{Cid: root.Cid(), Size: s})
+
+	nd := merkledag.NodeWithData(nil)
+	for _, l := range de.anc.ls {
+		err = nd.AddRawLink("", l)
+		if err != nil {
+			return err
+		}
+	}
+
+	end, err := de.e.Encode(ctx, nd, r)
+	if err != nil {
+		return err
+	}
+
+	err = de.anc.pi.Put(end)
+	if err != nil {
+		return err
+	}
+
+	if de.pr != nil {
+		var ps uint64
+		for _, l := range end.RecoveryLinks() {
+			ps += l.Size
+		}
+
+		de.report(EncodeEvent{Type: AnchorEncoded, Cid: id, Encoded: end.Cid(), Recoverability: r, ParityBytes: ps})
+	}
+
+	return nil
+}
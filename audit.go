@@ -0,0 +1,182 @@
+package recovery
+
+import (
+	"context"
+
+	"github.com/ipfs/go-cid"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	format "github.com/ipfs/go-ipld-format"
+)
+
+// NodeAudit describes local health of a recovery Node.
+type NodeAudit struct {
+	Cid            cid.Cid
+	Recoverability Recoverability
+
+	// Amounts of Data and Redundant Nodes linked and available locally.
+	Data, DataPresent     int
+	Parity, ParityPresent int
+
+	// DataLost is the amount of coding vectors lost with missing Data Nodes. It differs from the amount of missing
+	// Data Nodes if they are packed into vectors, e.g. with the striped Reed-Solomon layout.
+	DataLost int
+
+	margin *int // reported by the recovery Node itself
+}
+
+// Missing returns amount of linked Nodes not available locally.
+func (na *NodeAudit) Missing() int {
+	return na.Data - na.DataPresent + na.Parity - na.ParityPresent
+}
+
+// Margin returns amount of coding vectors that can be lost additionally preserving recoverability, which is the
+// amount of linked Nodes unless they are packed into vectors.
+// Negative margin means the Node can't guarantee recovery anymore.
+func (na *NodeAudit) Margin() int {
+	if na.margin != nil {
+		return *na.margin
+	}
+
+	return na.Recoverability - na.DataLost - (na.Parity - na.ParityPresent)
+}
+
+// vectorNode is implemented by recovery Nodes coding vectors other than their links, e.g. stripes packing children.
+type vectorNode interface {
+	// Vectors counts distinct data vectors spanned by the children at the given link positions.
+	Vectors(links ...int) int
+}
+
+// marginNode is implemented by recovery Nodes which losses don't consume Recoverability one by one, e.g. ones coding
+// groups of children independently or not using MDS codes.
+type marginNode interface {
+	// Margin returns the Margin of the Node given positions of missing linked and Redundant Nodes.
+	Margin(data, parity []int) int
+}
+
+// Recoverable reports whether all missing linked Nodes can be recovered.
+func (na *NodeAudit) Recoverable() bool {
+	return na.Margin() >= 0
+}
+
+// AuditReport describes local health of the whole DAG.
+type AuditReport struct {
+	// Nodes lists audits of all recovery Nodes in the DAG.
+	Nodes []*NodeAudit
+	// MinMargin is the lowest Margin across all the recovery Nodes, zero if there are none.
+	MinMargin int
+	// Unaudited lists missing Nodes which subtrees can't be audited.
+	Unaudited []cid.Cid
+}
+
+// Recoverable reports whether all recovery Nodes in the DAG are recoverable.
+func (ar *AuditReport) Recoverable() bool {
+	for _, na := range ar.Nodes {
+		if !na.Recoverable() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Audit walks the DAG under the root available in the Blockstore and reports recoverability of every recovery Node.
+// It does not fetch or recover anything.
+func Audit(ctx context.Context, bs blockstore.Blockstore, root cid.Cid) (*AuditReport, error) {
+	ar := &AuditReport{}
+	seen := cid.NewSet()
+
+	var audit func(cid.Cid) error
+	audit = func(id cid.Cid) error {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if !seen.Visit(id) {
+			return nil
+		}
+
+		b, err := bs.Get(id)
+		switch err {
+		case nil:
+		case blockstore.ErrNotFound:
+			ar.Unaudited = append(ar.Unaudited, id)
+			return nil
+		default:
+			return err
+		}
+
+		nd, err := format.Decode(b)
+		if err != nil {
+			return err
+		}
+
+		if rn, ok := nd.(Node); ok {
+			na, err := auditNode(bs, rn)
+			if err != nil {
+				return err
+			}
+
+			if len(ar.Nodes) == 0 || na.Margin() < ar.MinMargin {
+				ar.MinMargin = na.Margin()
+			}
+			ar.Nodes = append(ar.Nodes, na)
+		}
+
+		for _, l := range nd.Links() {
+			err = audit(l.Cid)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	return ar, audit(root)
+}
+
+func auditNode(bs blockstore.Blockstore, rn Node) (*NodeAudit, error) {
+	na := &NodeAudit{
+		Cid:            rn.Cid(),
+		Recoverability: rn.Recoverability(),
+		Data:           len(rn.Links()),
+		Parity:         len(rn.RecoveryLinks()),
+	}
+
+	var lost []int
+	for i, l := range rn.Links() {
+		ok, err := bs.Has(l.Cid)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			na.DataPresent++
+		} else {
+			lost = append(lost, i)
+		}
+	}
+
+	na.DataLost = len(lost)
+	if vn, ok := rn.(vectorNode); ok {
+		na.DataLost = vn.Vectors(lost...)
+	}
+
+	var plost []int
+	for i, l := range rn.RecoveryLinks() {
+		ok, err := bs.Has(l.Cid)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			na.ParityPresent++
+		} else {
+			plost = append(plost, i)
+		}
+	}
+
+	if mn, ok := rn.(marginNode); ok {
+		m := mn.Margin(lost, plost)
+		na.margin = &m
+	}
+
+	return na, nil
+}
@@ -0,0 +1,153 @@
+package recovery_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-blockservice"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/sync"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	offline "github.com/ipfs/go-ipfs-exchange-offline"
+	format "github.com/ipfs/go-ipld-format"
+	"github.com/ipfs/go-merkledag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	recovery "github.com/Wondertan/go-ipfs-recovery"
+	"github.com/Wondertan/go-ipfs-recovery/entanglement"
+	"github.com/Wondertan/go-ipfs-recovery/reedsolomon"
+)
+
+func TestAudit(t *testing.T) {
+	ctx := context.Background()
+	bstore := blockstore.NewBlockstore(sync.MutexWrap(datastore.NewMapDatastore()))
+	dag := merkledag.NewDAGService(blockservice.New(bstore, offline.Exchange(bstore)))
+
+	enc, err := recovery.EncodeDAG(ctx, dag, reedsolomon.NewEncoder(dag), newTestDAG(t, dag, 3), 2)
+	require.NoError(t, err)
+	root := enc.(recovery.Node)
+
+	ar, err := recovery.Audit(ctx, bstore, root.Cid())
+	require.NoError(t, err)
+	assert.Len(t, ar.Nodes, 4)
+	assert.Equal(t, 2, ar.MinMargin)
+	assert.True(t, ar.Recoverable())
+	assert.Empty(t, ar.Unaudited)
+
+	require.NoError(t, dag.Remove(ctx, root.Links()[0].Cid))
+	require.NoError(t, dag.Remove(ctx, root.RecoveryLinks()[0].Cid))
+	require.NoError(t, dag.Remove(ctx, root.RecoveryLinks()[1].Cid))
+
+	ar, err = recovery.Audit(ctx, bstore, root.Cid())
+	require.NoError(t, err)
+	assert.Len(t, ar.Nodes, 3)
+	assert.Equal(t, -1, ar.MinMargin)
+	assert.False(t, ar.Recoverable())
+	assert.Equal(t, []cid.Cid{root.Links()[0].Cid}, ar.Unaudited)
+
+	na := ar.Nodes[0]
+	assert.True(t, root.Cid().Equals(na.Cid))
+	assert.Equal(t, 2, na.Recoverability)
+	assert.Equal(t, 3, na.Data)
+	assert.Equal(t, 2, na.DataPresent)
+	assert.Equal(t, 2, na.Parity)
+	assert.Equal(t, 0, na.ParityPresent)
+	assert.Equal(t, 3, na.Missing())
+}
+
+func TestAuditStriped(t *testing.T) {
+	ctx := context.Background()
+	bstore := blockstore.NewBlockstore(sync.MutexWrap(datastore.NewMapDatastore()))
+	dag := merkledag.NewDAGService(blockservice.New(bstore, offline.Exchange(bstore)))
+
+	nd := merkledag.NodeWithData([]byte("striped"))
+	for _, size := range []int{2, 6, 4} {
+		ch := merkledag.NewRawNode(make([]byte, size))
+		ch.RawData()[0] = byte(size)
+		require.NoError(t, dag.Add(ctx, ch))
+		require.NoError(t, nd.AddNodeLink("", ch))
+	}
+	require.NoError(t, dag.Add(ctx, nd))
+
+	enc, err := recovery.EncodeDAG(ctx, dag, reedsolomon.NewStripedEncoder(dag, 4), nd, 1)
+	require.NoError(t, err)
+
+	// the child spans two stripes, while only one can be recovered
+	require.NoError(t, dag.Remove(ctx, enc.Links()[1].Cid))
+
+	ar, err := recovery.Audit(ctx, bstore, enc.Cid())
+	require.NoError(t, err)
+	require.Len(t, ar.Nodes, 1)
+
+	na := ar.Nodes[0]
+	assert.Equal(t, 1, na.Missing())
+	assert.Equal(t, 2, na.DataLost)
+	assert.Equal(t, -1, na.Margin())
+	assert.False(t, ar.Recoverable())
+}
+
+func TestAuditGroups(t *testing.T) {
+	ctx := context.Background()
+	bstore := blockstore.NewBlockstore(sync.MutexWrap(datastore.NewMapDatastore()))
+	dag := merkledag.NewDAGService(blockservice.New(bstore, offline.Exchange(bstore)))
+
+	nd := merkledag.NodeWithData([]byte("wide"))
+	chs := make([]format.Node, 300)
+	for i := range chs {
+		chs[i] = merkledag.NewRawNode([]byte{byte(i), byte(i >> 8)})
+		require.NoError(t, nd.AddNodeLink("", chs[i]))
+	}
+	require.NoError(t, dag.AddMany(ctx, append(chs, nd)))
+
+	enc, err := recovery.EncodeDAG(ctx, dag, reedsolomon.NewEncoder(dag), nd, 2)
+	require.NoError(t, err)
+
+	// groups are recovered independently, so losses in one don't consume recoverability of another
+	for _, ch := range []format.Node{chs[0], chs[1], chs[299]} {
+		require.NoError(t, dag.Remove(ctx, ch.Cid()))
+	}
+
+	ar, err := recovery.Audit(ctx, bstore, enc.Cid())
+	require.NoError(t, err)
+	assert.Equal(t, 0, ar.MinMargin)
+	assert.True(t, ar.Recoverable())
+
+	require.NoError(t, dag.Remove(ctx, chs[2].Cid()))
+
+	ar, err = recovery.Audit(ctx, bstore, enc.Cid())
+	require.NoError(t, err)
+	assert.Equal(t, -1, ar.MinMargin)
+	assert.False(t, ar.Recoverable())
+}
+
+func TestAuditEntangled(t *testing.T) {
+	ctx := context.Background()
+	bstore := blockstore.NewBlockstore(sync.MutexWrap(datastore.NewMapDatastore()))
+	dag := merkledag.NewDAGService(blockservice.New(bstore, offline.Exchange(bstore)))
+
+	nd := merkledag.NodeWithData([]byte("entangled"))
+	for i := 0; i < 10; i++ {
+		ch := merkledag.NewRawNode([]byte{byte(i)})
+		require.NoError(t, dag.Add(ctx, ch))
+		require.NoError(t, nd.AddNodeLink("", ch))
+	}
+	require.NoError(t, dag.Add(ctx, nd))
+
+	enc, err := recovery.EncodeDAG(ctx, dag, entanglement.NewEncoder(dag, 2, 2), nd, 2)
+	require.NoError(t, err)
+	rn := enc.(recovery.Node)
+
+	// every lost parity doesn't cost a unit of recoverability, as the lattice repairs it
+	for _, l := range rn.RecoveryLinks()[:3] {
+		require.NoError(t, dag.Remove(ctx, l.Cid))
+	}
+
+	ar, err := recovery.Audit(ctx, bstore, enc.Cid())
+	require.NoError(t, err)
+	require.Len(t, ar.Nodes, 1)
+	assert.Less(t, ar.Nodes[0].ParityPresent, ar.Nodes[0].Parity)
+	assert.GreaterOrEqual(t, ar.MinMargin, 0)
+	assert.True(t, ar.Recoverable())
+}
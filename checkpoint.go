@@ -0,0 +1,107 @@
+package recovery
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/namespace"
+	format "github.com/ipfs/go-ipld-format"
+	"github.com/multiformats/go-varint"
+)
+
+var checkpointsPrefix = datastore.NewKey("/recovery/checkpoints")
+
+// Checkpoint makes EncodeDAG persist encoding progress in the Datastore, so EncodeDAG called again for the same DAG
+// skips already encoded subtrees and continues from where it stopped.
+// Checkpoints record the Encoder and are checked against Recoverability chosen for the Node, so resuming with
+// different ones fails instead of mixing them within one DAG.
+func Checkpoint(ds datastore.Datastore) EncodeOption {
+	return func(o *encodeOptions) {
+		o.cp = &checkpoints{ds: namespace.Wrap(ds, checkpointsPrefix)}
+	}
+}
+
+// checkpoints persists mapping from original Nodes to encoded ones.
+type checkpoints struct {
+	ds datastore.Datastore
+	e  string // identifies the Encoder
+}
+
+// encoderID identifies the Encoder along with its parameters, if it describes itself.
+func encoderID(e Encoder) string {
+	if s, ok := e.(fmt.Stringer); ok {
+		return s.String()
+	}
+
+	return fmt.Sprintf("%T", e)
+}
+
+// Get returns id of the encoded Node for the original one, if it was encoded before with the same Encoder.
+func (c *checkpoints) Get(id cid.Cid) (cid.Cid, bool, error) {
+	v, err := c.ds.Get(datastore.NewKey(id.String()))
+	switch err {
+	case nil:
+	case datastore.ErrNotFound:
+		return cid.Undef, false, nil
+	default:
+		return cid.Undef, false, err
+	}
+
+	l, n, err := varint.FromUvarint(v)
+	if err != nil {
+		return cid.Undef, false, err
+	}
+	if l > uint64(len(v)-n) {
+		return cid.Undef, false, fmt.Errorf("recovery: wrong checkpoint for %s", id)
+	}
+	if e := string(v[n : n+int(l)]); e != c.e {
+		return cid.Undef, false, fmt.Errorf("recovery: %s is checkpointed with Encoder %s, not %s", id, e, c.e)
+	}
+
+	eid, err := cid.Cast(v[n+int(l):])
+	if err != nil {
+		return cid.Undef, false, err
+	}
+
+	return eid, true, nil
+}
+
+// Put remembers the encoded Node for the original one.
+func (c *checkpoints) Put(id cid.Cid, end format.Node) error {
+	l := uint64(len(c.e))
+	v := make([]byte, varint.UvarintSize(l), varint.UvarintSize(l)+len(c.e)+end.Cid().ByteLen())
+	varint.PutUvarint(v, l)
+	v = append(v, c.e...)
+	return c.ds.Put(datastore.NewKey(id.String()), append(v, end.Cid().Bytes()...))
+}
+
+// checkpointed returns the encoded Node for the original one, if it was encoded before.
+// It fails if Recoverability chosen for the Node now differs from the encoded one.
+func (de *dagEncoder) checkpointed(ctx context.Context, id cid.Cid, depth int) (format.Node, bool, error) {
+	if de.cp == nil {
+		return nil, false, nil
+	}
+
+	eid, ok, err := de.cp.Get(id)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+
+	end, err := de.dag.Get(ctx, eid)
+	if err != nil {
+		return nil, false, err
+	}
+
+	rn, ok := end.(Node)
+	if !ok {
+		return nil, false, fmt.Errorf("recovery: %s is checkpointed with not a recovery Node", id)
+	}
+	if r := de.rf(Unwrap(rn), depth); r != rn.Recoverability() {
+		return nil, false, fmt.Errorf("recovery: %s is checkpointed with Recoverability %d, not %d", id, rn.Recoverability(), r)
+	}
+
+	de.report(EncodeEvent{Type: SubtreeSkipped, Cid: id, Encoded: eid})
+	return end, true, nil
+}
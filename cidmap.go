@@ -0,0 +1,118 @@
+package recovery
+
+import (
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/namespace"
+	format "github.com/ipfs/go-ipld-format"
+	"github.com/multiformats/go-varint"
+)
+
+var (
+	cidMapPrefix  = datastore.NewKey("/recovery/originals")
+	encodedPrefix = datastore.NewKey("/recovery/encoded")
+)
+
+// CidMap is a persistent mapping from original Nodes to encoded ones.
+// It allows to get content by CIDs known before encoding.
+type CidMap struct {
+	ds  datastore.Datastore
+	enc datastore.Datastore // reverse mapping, so re-encoded Nodes are remapped
+}
+
+// NewCidMap creates new CidMap on top of the given Datastore.
+func NewCidMap(ds datastore.Datastore) *CidMap {
+	return &CidMap{ds: namespace.Wrap(ds, cidMapPrefix), enc: namespace.Wrap(ds, encodedPrefix)}
+}
+
+// Put remembers the encoded Node for the original one of the given size, so links to the original are rebuilt.
+func (cm *CidMap) Put(id, end cid.Cid, size uint64) error {
+	err := cm.ds.Put(datastore.NewKey(id.String()), end.Bytes())
+	if err != nil {
+		return err
+	}
+
+	v := make([]byte, varint.UvarintSize(size), varint.UvarintSize(size)+id.ByteLen())
+	varint.PutUvarint(v, size)
+	return cm.enc.Put(datastore.NewKey(end.String()), append(v, id.Bytes()...))
+}
+
+// Original returns the link to the original Node of the encoded one, if known.
+func (cm *CidMap) Original(end cid.Cid) (*format.Link, bool, error) {
+	v, err := cm.enc.Get(datastore.NewKey(end.String()))
+	switch err {
+	case nil:
+	case datastore.ErrNotFound:
+		return nil, false, nil
+	default:
+		return nil, false, err
+	}
+
+	size, n, err := varint.FromUvarint(v)
+	if err != nil {
+		return nil, false, err
+	}
+
+	id, err := cid.Cast(v[n:])
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &format.Link{Cid: id, Size: size}, true, nil
+}
+
+// Replace remaps the original Node of the encoded one, if known, to the Node replacing the encoded one.
+func (cm *CidMap) Replace(end, nend cid.Cid) error {
+	l, ok, err := cm.Original(end)
+	if err != nil || !ok {
+		return err
+	}
+
+	err = cm.Put(l.Cid, nend, l.Size)
+	if err != nil {
+		return err
+	}
+
+	return cm.enc.Delete(datastore.NewKey(end.String()))
+}
+
+// Get returns the encoded Node id for the original one, if known.
+func (cm *CidMap) Get(id cid.Cid) (cid.Cid, bool, error) {
+	v, err := cm.ds.Get(datastore.NewKey(id.String()))
+	switch err {
+	case nil:
+	case datastore.ErrNotFound:
+		return cid.Undef, false, nil
+	default:
+		return cid.Undef, false, err
+	}
+
+	end, err := cid.Cast(v)
+	if err != nil {
+		return cid.Undef, false, err
+	}
+
+	return end, true, nil
+}
+
+// KeepOriginals makes EncodeDAG keep original Nodes, which are removed once encoded otherwise,
+// and record mapping from them to the encoded ones in the CidMap.
+func KeepOriginals(cm *CidMap) EncodeOption {
+	return func(o *encodeOptions) {
+		o.cm = cm
+	}
+}
+
+// original maps the original Node to the encoded one.
+func (de *dagEncoder) original(orig, end format.Node) error {
+	if de.cm == nil || orig.Cid().Equals(end.Cid()) {
+		return nil
+	}
+
+	s, err := orig.Size()
+	if err != nil {
+		return err
+	}
+
+	return de.cm.Put(orig.Cid(), end.Cid(), s)
+}
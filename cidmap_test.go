@@ -0,0 +1,118 @@
+package recovery_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-blockservice"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/sync"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	offline "github.com/ipfs/go-ipfs-exchange-offline"
+	"github.com/ipfs/go-merkledag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	recovery "github.com/Wondertan/go-ipfs-recovery"
+	"github.com/Wondertan/go-ipfs-recovery/reedsolomon"
+)
+
+func TestKeepOriginals(t *testing.T) {
+	ctx := context.Background()
+
+	ds := sync.MutexWrap(datastore.NewMapDatastore())
+	bstore := blockstore.NewBlockstore(ds)
+	ex := offline.Exchange(bstore)
+	dag := merkledag.NewDAGService(blockservice.New(bstore, ex))
+	cm := recovery.NewCidMap(ds)
+
+	nd := newTestDAG(t, dag, 3)
+	out, err := recovery.EncodeDAG(ctx, dag, reedsolomon.NewEncoder(dag), nd, 2, recovery.KeepOriginals(cm))
+	require.NoError(t, err)
+
+	end, ok, err := cm.Get(nd.Cid())
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.True(t, out.Cid().Equals(end))
+
+	for i, l := range nd.Links() {
+		mustGet(t, dag, l.Cid) // originals are kept
+
+		end, ok, err := cm.Get(l.Cid)
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.True(t, out.Links()[i].Cid.Equals(end))
+	}
+
+	// only encoded Nodes are left
+	require.NoError(t, dag.Remove(ctx, nd.Cid()))
+	require.NoError(t, dag.RemoveMany(ctx, cids(nd.Links())))
+	// and some of them are lost
+	require.NoError(t, dag.Remove(ctx, out.Links()[1].Cid))
+
+	ses := recovery.NewDagSession(ctx, reedsolomon.NewRecoverer(ctx, dag, recovery.Requested), ex, bstore,
+		recovery.WithCidMap(cm))
+
+	// the original Nodes are rebuilt from the encoded ones
+	root, err := ses.Get(ctx, nd.Cid())
+	require.NoError(t, err)
+	assert.True(t, nd.Cid().Equals(root.Cid()))
+	assert.Equal(t, nd.RawData(), root.RawData())
+
+	for _, l := range nd.Links() {
+		ch, err := ses.Get(ctx, l.Cid)
+		require.NoError(t, err)
+		assert.True(t, l.Cid.Equals(ch.Cid()))
+	}
+
+	// and GetMany agrees, once the root is walked by the new session
+	ses = recovery.NewDagSession(ctx, reedsolomon.NewRecoverer(ctx, dag, recovery.Requested), ex, bstore,
+		recovery.WithCidMap(cm))
+	_, err = ses.Get(ctx, nd.Cid())
+	require.NoError(t, err)
+
+	ids := append(cids(nd.Links()), nd.Cid())
+	got := make(map[cid.Cid][]byte)
+	for no := range ses.GetMany(ctx, ids) {
+		require.NoError(t, no.Err)
+		got[no.Node.Cid()] = no.Node.RawData()
+	}
+	for _, id := range ids {
+		assert.Contains(t, got, id)
+	}
+	assert.Equal(t, nd.RawData(), got[nd.Cid()])
+}
+
+func TestKeepOriginalsRecover(t *testing.T) {
+	ctx := context.Background()
+
+	ds := sync.MutexWrap(datastore.NewMapDatastore())
+	bstore := blockstore.NewBlockstore(ds)
+	ex := offline.Exchange(bstore)
+	dag := merkledag.NewDAGService(blockservice.New(bstore, ex))
+	cm := recovery.NewCidMap(ds)
+
+	nd := newTestDAG(t, dag, 3)
+	_, err := recovery.EncodeDAG(ctx, dag, reedsolomon.NewEncoder(dag), nd, 2, recovery.KeepOriginals(cm))
+	require.NoError(t, err)
+
+	// originals are kept, but one of the leaves is lost
+	ch := mustGet(t, dag, nd.Links()[1].Cid)
+	lf := mustGet(t, dag, ch.Links()[0].Cid)
+	require.NoError(t, dag.Remove(ctx, lf.Cid()))
+
+	ses := recovery.NewDagSession(ctx, reedsolomon.NewRecoverer(ctx, dag, recovery.Requested), ex, bstore,
+		recovery.WithCidMap(cm))
+
+	root, err := ses.Get(ctx, nd.Cid())
+	require.NoError(t, err)
+	assert.True(t, nd.Cid().Equals(root.Cid()))
+
+	_, err = ses.Get(ctx, ch.Cid())
+	require.NoError(t, err)
+
+	got, err := ses.Get(ctx, lf.Cid())
+	require.NoError(t, err)
+	assert.Equal(t, lf.RawData(), got.RawData())
+}
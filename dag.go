@@ -2,36 +2,299 @@ package recovery
 
 import (
 	"context"
+	"fmt"
+	"sync"
 
+	"github.com/ipfs/go-cid"
 	format "github.com/ipfs/go-ipld-format"
 )
 
+// EncodeOption configures EncodeDAG.
+type EncodeOption func(*encodeOptions)
+
+type encodeOptions struct {
+	concurrency int
+	cp          *checkpoints
+	progress    ProgressSink
+	rf          RecoverabilityFunc
+	anchor      Recoverability
+	pi          *ParentIndex
+	cm          *CidMap
+}
+
+// Concurrency sets maximum amount of subtrees encoded in parallel. Subtrees are encoded sequentially by default.
+func Concurrency(n int) EncodeOption {
+	return func(o *encodeOptions) {
+		if n > 0 {
+			o.concurrency = n
+		}
+	}
+}
+
 // EncodeDAG encodes whole DAG under the given node with given Encoder and recoverability.
-func EncodeDAG(ctx context.Context, dag format.NodeGetter, e Encoder, nd format.Node, r Recoverability) (format.Node, error) {
+// Encoded original Nodes are removed, so the NodeGetter must be able to remove them, unless KeepOriginals is used.
+// Encoder writing to the DAG wrapped with NewIndexedDAG populates the ParentIndex along the way.
+func EncodeDAG(ctx context.Context, dag format.NodeGetter, e Encoder, nd format.Node, r Recoverability, opts ...EncodeOption) (format.Node, error) {
+	de, o := newDagEncoder(dag, e, r, opts...)
+	if _, ok := dag.(nodeRemover); !ok && o.cm == nil {
+		// checked beforehand to not leave the DAG partially encoded
+		return nil, fmt.Errorf("recovery: can't remove original Nodes with the NodeGetter, use KeepOriginals to keep them")
+	}
+	if o.anchor > 0 {
+		if o.pi == nil {
+			return nil, fmt.Errorf("recovery: anchor needs ParentIndex to be recorded in")
+		}
+
+		de.anc = &anchors{pi: o.pi}
+	}
+
+	end, err := de.encodeRoot(ctx, nd)
+	if err != nil || de.anc == nil {
+		return end, err
+	}
+
+	return end, de.anchor(ctx, nd.Cid(), end, o.anchor)
+}
+
+func newDagEncoder(dag format.NodeGetter, e Encoder, r Recoverability, opts ...EncodeOption) (*dagEncoder, *encodeOptions) {
+	o := &encodeOptions{
+		concurrency: 1,
+		rf: func(format.Node, int) Recoverability {
+			return r
+		},
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	de := &dagEncoder{
+		dag: dag,
+		e:   e,
+		rf:  o.rf,
+		sem: make(chan struct{}, o.concurrency-1), // the calling goroutine is a worker too
+		cp:  o.cp,
+		pr:  o.progress,
+		cm:  o.cm,
+	}
+	if de.cp != nil {
+		de.cp.e = encoderID(e)
+	}
+
+	return de, o
+}
+
+func (de *dagEncoder) encodeRoot(ctx context.Context, nd format.Node) (format.Node, error) {
+	end, ok, err := de.checkpointed(ctx, nd.Cid(), 0)
+	if err != nil || ok {
+		return end, err
+	}
+
+	return de.encode(ctx, nd, 0)
+}
+
+type dagEncoder struct {
+	dag format.NodeGetter
+	e   Encoder
+	rf  RecoverabilityFunc
+
+	sem chan struct{}
+	cp  *checkpoints
+	pr  ProgressSink
+	anc *anchors
+	cm  *CidMap
+	sc  *sidecar
+}
+
+func (de *dagEncoder) report(e EncodeEvent) {
+	if de.pr != nil {
+		de.pr(e)
+	}
+}
+
+func (de *dagEncoder) encode(ctx context.Context, nd format.Node, depth int) (format.Node, error) {
+	de.report(EncodeEvent{Type: NodeVisited, Cid: nd.Cid()})
 	if len(nd.Links()) == 0 {
+		de.report(EncodeEvent{Type: LeafSkipped, Cid: nd.Cid()})
 		return nd, nil
 	}
+	orig, r := nd, de.rf(nd, depth)
+	id := orig.Cid()
+
+	// links are updated in place, so copy them to not alter the original Node.
+	nd = nd.Copy()
+	for i, l := range nd.Links() {
+		cp := *l
+		nd.Links()[i] = &cp
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg   sync.WaitGroup
+		once sync.Once
+		err  error
+	)
+	fail := func(e error) {
+		once.Do(func() {
+			err = e
+			cancel()
+		})
+	}
 
 	for _, l := range nd.Links() {
-		nd, err := l.GetNode(ctx, dag)
+		if ctx.Err() != nil {
+			break
+		}
+
+		// encode subtree in parallel if there is a free worker, otherwise do it in place.
+		select {
+		case de.sem <- struct{}{}:
+			wg.Add(1)
+			go func(l *format.Link) {
+				defer func() {
+					<-de.sem
+					wg.Done()
+				}()
+
+				if err := de.encodeLink(ctx, l, depth+1); err != nil {
+					fail(err)
+				}
+			}(l)
+		default:
+			if err := de.encodeLink(ctx, l, depth+1); err != nil {
+				fail(err)
+			}
+		}
+	}
+
+	wg.Wait()
+	if err != nil {
+		return nil, err
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	nd, err = relinked(orig, nd)
+	if err != nil {
+		return nil, err
+	}
+
+	if de.sc != nil {
+		return orig, de.sidecar(ctx, orig, nd, r)
+	}
+
+	if r <= 0 {
+		if de.anc != nil {
+			de.anc.Add(nd.Links()...)
+		}
+
+		end, err := de.keep(ctx, id, nd)
 		if err != nil {
 			return nil, err
 		}
 
-		end, err := EncodeDAG(ctx, dag, e, nd, r)
+		return end, de.original(orig, end)
+	}
+
+	end, err := de.e.Encode(ctx, nd, r)
+	if err != nil {
+		return nil, err
+	}
+
+	if de.pr != nil {
+		var ps uint64
+		for _, l := range end.RecoveryLinks() {
+			ps += l.Size
+		}
+
+		de.report(EncodeEvent{Type: NodeEncoded, Cid: id, Encoded: end.Cid(), Recoverability: r, ParityBytes: ps})
+	}
+
+	err = de.original(orig, end)
+	if err != nil {
+		return nil, err
+	}
+
+	if de.cp != nil {
+		err = de.cp.Put(id, end)
 		if err != nil {
 			return nil, err
 		}
+	}
 
-		if !nd.Cid().Equals(end.Cid()) {
-			l.Size, err = end.Size()
-			if err != nil {
-				return nil, err
-			}
+	// the original is removed only once the encoded Node is checkpointed, so encoding can always be resumed.
+	return end, de.remove(ctx, orig)
+}
+
+// nodeRemover is a NodeGetter able to remove Nodes, like DAGService.
+type nodeRemover interface {
+	Remove(context.Context, cid.Cid) error
+}
+
+// remove drops the encoded original Node, unless originals are kept.
+func (de *dagEncoder) remove(ctx context.Context, orig format.Node) error {
+	if de.cm != nil {
+		return nil
+	}
 
-			l.Cid = end.Cid()
+	nr, ok := de.dag.(nodeRemover)
+	if !ok {
+		return fmt.Errorf("recovery: can't remove original Node with the NodeGetter")
+	}
+
+	return nr.Remove(ctx, orig.Cid())
+}
+
+// keep stores the Node left unencoded, if its links were changed.
+func (de *dagEncoder) keep(ctx context.Context, id cid.Cid, nd format.Node) (format.Node, error) {
+	if nd.Cid().Equals(id) {
+		return nd, nil
+	}
+
+	na, ok := de.dag.(format.NodeAdder)
+	if !ok {
+		return nil, fmt.Errorf("recovery: can't store unencoded Node with the NodeGetter")
+	}
+
+	return nd, na.Add(ctx, nd)
+}
+
+// encodeLink encodes the subtree under the link and updates the link to point to the encoded one.
+func (de *dagEncoder) encodeLink(ctx context.Context, l *format.Link, depth int) error {
+	end, ok, err := de.checkpointed(ctx, l.Cid, depth)
+	if err != nil {
+		return err
+	}
+	if ok {
+		l.Size, err = end.Size()
+		if err != nil {
+			return err
 		}
+
+		l.Cid = end.Cid()
+		return nil
+	}
+
+	nd, err := l.GetNode(ctx, de.dag)
+	if err != nil {
+		return err
+	}
+
+	end, err = de.encode(ctx, nd, depth)
+	if err != nil {
+		return err
+	}
+
+	if !nd.Cid().Equals(end.Cid()) {
+		l.Size, err = end.Size()
+		if err != nil {
+			return err
+		}
+
+		l.Cid = end.Cid()
 	}
 
-	return e.Encode(ctx, nd, r)
+	return nil
 }
@@ -3,6 +3,7 @@ package recovery
 import (
 	"context"
 	"sync"
+	"time"
 
 	blocks "github.com/ipfs/go-block-format"
 	"github.com/ipfs/go-cid"
@@ -22,23 +23,103 @@ type dagSession struct {
 	ex exchange.Interface
 	bs blockstore.Blockstore
 
-	prnts map[cid.Cid]Node
-	pl    sync.Mutex
+	prnts  *parents
+	orgs   *origins
+	limit  int
+	picker ParentPicker
 
 	f  exchange.Fetcher
 	fo sync.Once
+
+	plc Policy
+	nt  time.Duration
+	pi  *ParentIndex
+	cm  *CidMap
+	mf  *Manifests
+}
+
+// SessionOption configures the session created with NewDagSession.
+type SessionOption func(*dagSession)
+
+// WithPolicy sets the Policy the session uses to get missing Nodes. RecoverFirst is used by default.
+// GetMany always races recovery and the network.
+func WithPolicy(plc Policy) SessionOption {
+	return func(ds *dagSession) {
+		ds.plc = plc
+	}
 }
 
-func NewDagSession(ctx context.Context, r Recoverer, ex exchange.Interface, bs blockstore.Blockstore) format.NodeGetter {
-	return &dagSession{
-		ctx:   ctx,
-		r:     r,
-		ex:    ex,
-		bs:    bs,
-		prnts: make(map[cid.Cid]Node),
+// DefaultNetworkTimeout is the default time NetworkFirst Policy waits for the network before falling back to recovery.
+const DefaultNetworkTimeout = 10 * time.Second
+
+// WithNetworkTimeout sets the time NetworkFirst Policy waits for the network before falling back to recovery.
+// DefaultNetworkTimeout is used by default. Non-positive timeout makes the session wait until the network reports the
+// Node as missing or the request is done.
+func WithNetworkTimeout(d time.Duration) SessionOption {
+	return func(ds *dagSession) {
+		ds.nt = d
 	}
 }
 
+// WithParentIndex sets the ParentIndex the session uses to find recovery Nodes for Nodes requested directly.
+func WithParentIndex(pi *ParentIndex) SessionOption {
+	return func(ds *dagSession) {
+		ds.pi = pi
+	}
+}
+
+// WithCidMap sets the CidMap the session uses to resolve Nodes requested by their original CIDs through the encoded
+// ones. Missing original Nodes are rebuilt from the encoded ones, linking original children back.
+// Children missing from kept original Nodes are recovered through the encoded ones as well.
+func WithCidMap(cm *CidMap) SessionOption {
+	return func(ds *dagSession) {
+		ds.cm = cm
+	}
+}
+
+// WithManifests sets the Manifests the session uses to find detached recovery Nodes for Nodes of unmodified DAGs.
+// They are looked up only once children of such Nodes are missing.
+func WithManifests(mf *Manifests) SessionOption {
+	return func(ds *dagSession) {
+		ds.mf = mf
+	}
+}
+
+// WithParentsLimit sets the amount of recovery Nodes cached by the session. DefaultParentsLimit is used by default.
+// Non-positive limit disables eviction.
+func WithParentsLimit(limit int) SessionOption {
+	return func(ds *dagSession) {
+		ds.limit = limit
+	}
+}
+
+// WithParentPicker sets the ParentPicker for Nodes linked from multiple recovery Nodes. PickFirst is used by default.
+func WithParentPicker(picker ParentPicker) SessionOption {
+	return func(ds *dagSession) {
+		ds.picker = picker
+	}
+}
+
+func NewDagSession(ctx context.Context, r Recoverer, ex exchange.Interface, bs blockstore.Blockstore, opts ...SessionOption) format.NodeGetter {
+	ds := &dagSession{
+		ctx:    ctx,
+		r:      r,
+		ex:     ex,
+		bs:     bs,
+		plc:    RecoverFirst,
+		nt:     DefaultNetworkTimeout,
+		limit:  DefaultParentsLimit,
+		picker: PickFirst,
+	}
+	for _, opt := range opts {
+		opt(ds)
+	}
+
+	ds.prnts = newParents(ctx, ds.limit)
+	ds.orgs = newOrigins(ds.limit)
+	return ds
+}
+
 func (ds *dagSession) Get(ctx context.Context, id cid.Cid) (format.Node, error) {
 	err := verifcid.ValidateCid(id)
 	if err != nil {
@@ -55,24 +136,133 @@ func (ds *dagSession) Get(ctx context.Context, id cid.Cid) (format.Node, error)
 	case blockstore.ErrNotFound:
 	}
 
-	// 2. Try to recover.
-	nd, err := ds.recover(ctx, id)
-	if err != format.ErrNotFound {
+	// 2. Try to rebuild the original Node from the encoded one.
+	nd, ok, err := ds.original(ctx, id)
+	if ok {
 		return nd, err
 	}
 
-	// 3. Try to get from the network.
-	b, err = ds.fetcher().GetBlock(ctx, id)
+	// 3. Try to recover and/or to get from the network.
+	switch ds.plc {
+	case NetworkFirst:
+		nd, err := ds.fetchWithin(ctx, id)
+		if err != format.ErrNotFound {
+			return nd, err
+		}
+
+		return ds.recover(ctx, id)
+	case Race:
+		return ds.race(ctx, id)
+	default:
+		nd, err := ds.recover(ctx, id)
+		if err != format.ErrNotFound {
+			return nd, err
+		}
+
+		return ds.fetch(ctx, id)
+	}
+}
+
+// original rebuilds the original Node from the encoded one, if the CidMap knows it, linking original children instead
+// of encoded ones. The rebuilt Node must match the original CID.
+func (ds *dagSession) original(ctx context.Context, id cid.Cid) (format.Node, bool, error) {
+	if ds.cm == nil {
+		return nil, false, nil
+	}
+
+	end, ok, err := ds.cm.Get(id)
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+
+	nd, err := ds.Get(ctx, end)
+	if err != nil {
+		return nil, true, err
+	}
+
+	orig := nd.Copy()
+	for i, l := range orig.Links() {
+		cp := *l
+		ol, ok, err := ds.cm.Original(l.Cid)
+		if err != nil {
+			return nil, true, err
+		}
+		if ok {
+			cp.Cid, cp.Size = ol.Cid, ol.Size
+		}
+
+		orig.Links()[i] = &cp
+	}
+
+	orig, err = relinked(nd, orig)
+	if err != nil {
+		return nil, true, err
+	}
+	if !orig.Cid().Equals(id) {
+		log.Warnf("Can't rebuild original Node(%s) from %s", id, end)
+		return nil, true, format.ErrNotFound
+	}
+
+	return orig, true, nil
+}
+
+// race recovers and fetches the Node in parallel, the loser is cancelled.
+func (ds *dagSession) race(ctx context.Context, id cid.Cid) (format.Node, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	res := make(chan *format.NodeOption, 2)
+	go func() {
+		nd, err := ds.recover(ctx, id)
+		res <- &format.NodeOption{Node: nd, Err: err}
+	}()
+	go func() {
+		nd, err := ds.fetch(ctx, id)
+		res <- &format.NodeOption{Node: nd, Err: err}
+	}()
+
+	err := format.ErrNotFound
+	for i := 0; i < 2; i++ {
+		no := <-res
+		if no.Err == nil {
+			return no.Node, nil
+		}
+		if no.Err != format.ErrNotFound {
+			err = no.Err
+		}
+	}
+
+	return nil, err
+}
+
+// fetch gets the Node from the network.
+func (ds *dagSession) fetch(ctx context.Context, id cid.Cid) (format.Node, error) {
+	b, err := ds.fetcher().GetBlock(ctx, id)
 	switch err {
 	default:
 		return nil, err
 	case nil:
 		return ds.decode(b)
 	case blockstore.ErrNotFound:
+		return nil, format.ErrNotFound
+	}
+}
+
+// fetchWithin gets the Node from the network within the network timeout, reporting it as missing once it is exceeded.
+func (ds *dagSession) fetchWithin(ctx context.Context, id cid.Cid) (format.Node, error) {
+	if ds.nt <= 0 {
+		return ds.fetch(ctx, id)
+	}
+
+	fctx, cancel := context.WithTimeout(ctx, ds.nt)
+	defer cancel()
+
+	nd, err := ds.fetch(fctx, id)
+	if err != nil && fctx.Err() != nil && ctx.Err() == nil {
+		return nil, format.ErrNotFound
 	}
 
-	// 4. Fail :(
-	return nil, format.ErrNotFound
+	return nd, err
 }
 
 func (ds *dagSession) GetMany(ctx context.Context, in []cid.Cid) <-chan *format.NodeOption {
@@ -101,17 +291,6 @@ func (ds *dagSession) GetMany(ctx context.Context, in []cid.Cid) <-chan *format.
 				}
 			}
 
-			// TODO: Recovery might be long blocking, so run it async
-			nd, err := ds.recover(ctx, id)
-			if err == nil {
-				select {
-				case out <- &format.NodeOption{Node: nd}:
-					continue
-				case <-ctx.Done():
-					return
-				}
-			}
-
 			ids[i] = id
 			i++
 		}
@@ -121,27 +300,82 @@ func (ds *dagSession) GetMany(ctx context.Context, in []cid.Cid) <-chan *format.
 			return
 		}
 
-		bs, err := ds.fetcher().GetBlocks(ctx, ids)
-		if err != nil {
-			return
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		// Recovery and the network race for every missing Node, the first one to get it wins.
+		rs := newResolver(ids, out, cancel)
+		wg := &sync.WaitGroup{}
+		for _, id := range ids {
+			wg.Add(1)
+			go func(id cid.Cid) {
+				defer wg.Done()
+
+				nd, ok, err := ds.original(ctx, id)
+				if !ok {
+					nd, err = ds.recover(ctx, id)
+				}
+				if err == nil {
+					rs.resolve(id, nd, nil)
+				}
+			}(id)
 		}
 
-		for b := range bs {
-			nd, err := ds.decode(b)
-			select {
-			case out <- &format.NodeOption{Node: nd, Err: err}:
-				continue
-			case <-ctx.Done():
-				return
+		bs, err := ds.fetcher().GetBlocks(ctx, ids)
+		if err == nil {
+			for b := range bs {
+				nd, err := ds.decode(b)
+				rs.resolve(b.Cid(), nd, err)
 			}
 		}
+
+		wg.Wait()
 	}()
 
 	return out
 }
 
+// resolver makes sure every requested Node is sent only once.
+type resolver struct {
+	out    chan<- *format.NodeOption
+	cancel context.CancelFunc
+
+	ids  map[cid.Cid]bool
+	left int
+	l    sync.Mutex
+}
+
+func newResolver(ids []cid.Cid, out chan<- *format.NodeOption, cancel context.CancelFunc) *resolver {
+	rs := &resolver{out: out, cancel: cancel, ids: make(map[cid.Cid]bool, len(ids))}
+	for _, id := range ids {
+		rs.ids[id] = false
+	}
+	rs.left = len(rs.ids)
+
+	return rs
+}
+
+// resolve sends the Node if it is not sent yet and cancels all the work once everything is resolved.
+func (rs *resolver) resolve(id cid.Cid, nd format.Node, err error) {
+	rs.l.Lock()
+	defer rs.l.Unlock()
+
+	rslvd, ok := rs.ids[id]
+	if !ok || rslvd {
+		return
+	}
+
+	rs.ids[id] = true
+	rs.out <- &format.NodeOption{Node: nd, Err: err} // never blocks, as out is buffered for all the ids
+
+	rs.left--
+	if rs.left == 0 {
+		rs.cancel()
+	}
+}
+
 func (ds *dagSession) recover(ctx context.Context, id cid.Cid) (format.Node, error) {
-	prnt := ds.getParentFor(id)
+	prnt := ds.parentFor(ctx, id)
 	if prnt == nil {
 		return nil, format.ErrNotFound
 	}
@@ -153,9 +387,13 @@ func (ds *dagSession) recover(ctx context.Context, id cid.Cid) (format.Node, err
 	}
 
 	select {
-	case no := <-nds:
+	case no, ok := <-nds:
+		if !ok {
+			log.Warnf("Recovery attempt failed(%s): no response", id)
+			return nil, format.ErrNotFound
+		}
 		if no.Err != nil {
-			log.Warnf("Recovery attempt failed(%s): %s", id, err)
+			log.Warnf("Recovery attempt failed(%s): %s", id, no.Err)
 			return nil, format.ErrNotFound
 		}
 
@@ -189,30 +427,88 @@ func (ds *dagSession) decode(b blocks.Block) (format.Node, error) {
 
 	rn, ok := nd.(Node)
 	if !ok {
+		if (ds.cm != nil || ds.mf != nil) && len(nd.Links()) > 0 {
+			ds.orgs.Add(nd)
+		}
+
 		return nd, nil
 	}
 
-	ds.pl.Lock()
-	ds.prnts[rn.Cid()] = rn.Copy().(Node) // it is better to make a copy here, since node can be altered by the caller.
-	ds.pl.Unlock()
+	ds.prnts.Add(rn.Copy().(Node)) // it is better to make a copy here, since node can be altered by the caller.
 
-	return rn.Proto(), nil
+	return Unwrap(rn), nil
 }
 
-// getParentFor tries to find the parent gotten within the session for the given CID.
-func (ds *dagSession) getParentFor(id cid.Cid) Node {
-	ds.pl.Lock()
-	defer ds.pl.Unlock()
-
-	for _, n := range ds.prnts {
-		for _, l := range n.Links() {
-			if l.Cid.Equals(id) {
-				// restoration reconstructs all linked nodes at fo hence parent can't be reused, so uncache it.
-			//	delete(ds.prnts, p)
-				return n
-			}
+// parentFor finds the recovery Node for the given CID, either gotten within the session or known to the ParentIndex,
+// or the one encoded from or detached for the unencoded Node linking it.
+func (ds *dagSession) parentFor(ctx context.Context, id cid.Cid) Node {
+	prnt := ds.getParentFor(id)
+	if prnt != nil {
+		return prnt
+	}
+
+	for _, pid := range ds.parentIDs(id) {
+		_, err := ds.Get(ctx, pid) // caches the parent, recovering it if needed
+		if err != nil {
+			log.Warnf("Can't get parent(%s) for %s: %s", pid, id, err)
+			continue
+		}
+
+		prnt = ds.getParentFor(id)
+		if prnt != nil {
+			return prnt
 		}
 	}
 
 	return nil
 }
+
+// parentIDs lists recovery Nodes which may link the given CID, but are not gotten within the session yet.
+func (ds *dagSession) parentIDs(id cid.Cid) []cid.Cid {
+	var ids []cid.Cid
+	if ds.pi != nil {
+		pids, err := ds.pi.Get(id)
+		if err != nil {
+			log.Errorf("Can't get parents(%s): %s", id, err)
+		}
+
+		ids = append(ids, pids...)
+	}
+
+	for _, oid := range ds.orgs.Get(id) {
+		if ds.cm != nil {
+			end, ok, err := ds.cm.Get(oid)
+			if err != nil {
+				log.Errorf("Can't get encoded Node(%s): %s", oid, err)
+			}
+			if ok {
+				ids = append(ids, end)
+			}
+		}
+
+		if ds.mf != nil {
+			rid, ok, err := ds.mf.Get(oid)
+			if err != nil {
+				log.Errorf("Can't get sidecar(%s): %s", oid, err)
+			}
+			if ok {
+				ids = append(ids, rid)
+			}
+		}
+	}
+
+	return ids
+}
+
+// getParentFor tries to find the parent gotten within the session for the given CID.
+func (ds *dagSession) getParentFor(id cid.Cid) Node {
+	prnts := ds.prnts.Get(id)
+	switch len(prnts) {
+	case 0:
+		return nil
+	case 1:
+		return prnts[0]
+	default:
+		return ds.picker(id, prnts)
+	}
+}
@@ -0,0 +1,220 @@
+package recovery_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-blockservice"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/sync"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	offline "github.com/ipfs/go-ipfs-exchange-offline"
+	format "github.com/ipfs/go-ipld-format"
+	"github.com/ipfs/go-merkledag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	recovery "github.com/Wondertan/go-ipfs-recovery"
+	"github.com/Wondertan/go-ipfs-recovery/reedsolomon"
+)
+
+func TestDagSessionGetMany(t *testing.T) {
+	ctx := context.Background()
+
+	bstore := blockstore.NewBlockstore(sync.MutexWrap(datastore.NewMapDatastore()))
+	ex := offline.Exchange(bstore)
+	dag := merkledag.NewDAGService(blockservice.New(bstore, ex))
+	ses := recovery.NewDagSession(ctx, reedsolomon.NewRecoverer(ctx, dag, recovery.Requested), ex, bstore)
+
+	prnt := merkledag.NodeWithData([]byte("1234567890"))
+	chs := []format.Node{
+		merkledag.NodeWithData([]byte("03243423423423")),
+		merkledag.NodeWithData([]byte("123450")),
+		merkledag.NodeWithData([]byte("1234509876")),
+		merkledag.NodeWithData([]byte("0987654321")),
+	}
+	ids := make([]cid.Cid, len(chs))
+	for i, ch := range chs {
+		ids[i] = ch.Cid()
+		prnt.AddNodeLink("link", ch)
+	}
+	require.NoError(t, dag.AddMany(ctx, append(chs, prnt)))
+
+	enc, err := reedsolomon.Encode(ctx, dag, prnt, 2)
+	require.NoError(t, err)
+
+	_, err = ses.Get(ctx, enc.Cid())
+	require.NoError(t, err)
+
+	dag.Remove(ctx, chs[0].Cid())
+	dag.Remove(ctx, chs[2].Cid())
+
+	got := make(map[cid.Cid][]byte)
+	for no := range ses.GetMany(ctx, ids) {
+		require.NoError(t, no.Err)
+		got[no.Node.Cid()] = no.Node.RawData()
+	}
+
+	require.Len(t, got, len(chs))
+	for _, ch := range chs {
+		assert.Equal(t, ch.RawData(), got[ch.Cid()])
+	}
+}
+
+func TestDagSessionGetPolicies(t *testing.T) {
+	for _, plc := range []recovery.Policy{recovery.RecoverFirst, recovery.NetworkFirst, recovery.Race} {
+		t.Run(string(plc), func(t *testing.T) {
+			ctx := context.Background()
+
+			bstore := blockstore.NewBlockstore(sync.MutexWrap(datastore.NewMapDatastore()))
+			dag := merkledag.NewDAGService(blockservice.New(bstore, offline.Exchange(bstore)))
+
+			// remote stands for a peer in the network
+			remote := blockstore.NewBlockstore(sync.MutexWrap(datastore.NewMapDatastore()))
+			ses := recovery.NewDagSession(
+				ctx,
+				reedsolomon.NewRecoverer(ctx, dag, recovery.Requested),
+				offline.Exchange(remote),
+				bstore,
+				recovery.WithPolicy(plc),
+			)
+
+			prnt := merkledag.NodeWithData([]byte("1234567890"))
+			ch1 := merkledag.NodeWithData([]byte("03243423423423"))
+			ch2 := merkledag.NodeWithData([]byte("123450"))
+			ch3 := merkledag.NodeWithData([]byte("1234509876"))
+			prnt.AddNodeLink("link", ch1)
+			prnt.AddNodeLink("link", ch2)
+			require.NoError(t, dag.AddMany(ctx, []format.Node{prnt, ch1, ch2}))
+
+			enc, err := reedsolomon.Encode(ctx, dag, prnt, 1)
+			require.NoError(t, err)
+
+			_, err = ses.Get(ctx, enc.Cid())
+			require.NoError(t, err)
+
+			// only recoverable
+			dag.Remove(ctx, ch1.Cid())
+			nd, err := ses.Get(ctx, ch1.Cid())
+			require.NoError(t, err)
+			assert.Equal(t, ch1.RawData(), nd.RawData())
+
+			// only in the network
+			require.NoError(t, remote.Put(ch3))
+			nd, err = ses.Get(ctx, ch3.Cid())
+			require.NoError(t, err)
+			assert.Equal(t, ch3.RawData(), nd.RawData())
+
+			// nowhere
+			_, err = ses.Get(ctx, merkledag.NodeWithData([]byte("lost")).Cid())
+			assert.Equal(t, format.ErrNotFound, err)
+		})
+	}
+}
+
+// silentRecoverer closes response channels without responding, like a Recoverer being closed.
+type silentRecoverer struct{}
+
+func (silentRecoverer) Recover(context.Context, recovery.Node, ...cid.Cid) (<-chan *format.NodeOption, error) {
+	out := make(chan *format.NodeOption)
+	close(out)
+	return out, nil
+}
+
+func (silentRecoverer) Close() error {
+	return nil
+}
+
+func TestDagSessionRecoverNoResponse(t *testing.T) {
+	ctx := context.Background()
+
+	bstore := blockstore.NewBlockstore(sync.MutexWrap(datastore.NewMapDatastore()))
+	ex := offline.Exchange(bstore)
+	dag := merkledag.NewDAGService(blockservice.New(bstore, ex))
+	ses := recovery.NewDagSession(ctx, silentRecoverer{}, ex, bstore)
+
+	prnt := merkledag.NodeWithData([]byte("1234567890"))
+	ch1 := merkledag.NodeWithData([]byte("03243423423423"))
+	ch2 := merkledag.NodeWithData([]byte("123450"))
+	prnt.AddNodeLink("link", ch1)
+	prnt.AddNodeLink("link", ch2)
+	require.NoError(t, dag.AddMany(ctx, []format.Node{prnt, ch1, ch2}))
+
+	enc, err := reedsolomon.Encode(ctx, dag, prnt, 1)
+	require.NoError(t, err)
+
+	_, err = ses.Get(ctx, enc.Cid())
+	require.NoError(t, err)
+
+	dag.Remove(ctx, ch1.Cid())
+	_, err = ses.Get(ctx, ch1.Cid())
+	assert.Equal(t, format.ErrNotFound, err)
+}
+
+// blockingExchange never finds anything and waits until the request is done, like Bitswap does.
+type blockingExchange struct{}
+
+func (blockingExchange) GetBlock(ctx context.Context, _ cid.Cid) (blocks.Block, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func (blockingExchange) GetBlocks(ctx context.Context, _ []cid.Cid) (<-chan blocks.Block, error) {
+	out := make(chan blocks.Block)
+	go func() {
+		<-ctx.Done()
+		close(out)
+	}()
+	return out, nil
+}
+
+func (blockingExchange) HasBlock(blocks.Block) error {
+	return nil
+}
+
+func (blockingExchange) IsOnline() bool {
+	return true
+}
+
+func (blockingExchange) Close() error {
+	return nil
+}
+
+func TestDagSessionNetworkTimeout(t *testing.T) {
+	ctx := context.Background()
+
+	bstore := blockstore.NewBlockstore(sync.MutexWrap(datastore.NewMapDatastore()))
+	dag := merkledag.NewDAGService(blockservice.New(bstore, offline.Exchange(bstore)))
+	ses := recovery.NewDagSession(
+		ctx,
+		reedsolomon.NewRecoverer(ctx, dag, recovery.Requested),
+		blockingExchange{},
+		bstore,
+		recovery.WithPolicy(recovery.NetworkFirst),
+		recovery.WithNetworkTimeout(50*time.Millisecond),
+	)
+
+	prnt := merkledag.NodeWithData([]byte("1234567890"))
+	ch1 := merkledag.NodeWithData([]byte("03243423423423"))
+	ch2 := merkledag.NodeWithData([]byte("123450"))
+	prnt.AddNodeLink("link", ch1)
+	prnt.AddNodeLink("link", ch2)
+	require.NoError(t, dag.AddMany(ctx, []format.Node{prnt, ch1, ch2}))
+
+	enc, err := reedsolomon.Encode(ctx, dag, prnt, 1)
+	require.NoError(t, err)
+
+	_, err = ses.Get(ctx, enc.Cid())
+	require.NoError(t, err)
+
+	// the network never answers, so the Node is recovered once the network timeout is exceeded
+	dag.Remove(ctx, ch1.Cid())
+	rctx, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	nd, err := ses.Get(rctx, ch1.Cid())
+	require.NoError(t, err)
+	assert.Equal(t, ch1.RawData(), nd.RawData())
+}
@@ -1,9 +1,351 @@
-package recovery
+package recovery_test
 
 import (
+	"context"
+	"fmt"
+	"sync"
 	"testing"
+
+	"github.com/ipfs/go-blockservice"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	dsync "github.com/ipfs/go-datastore/sync"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	offline "github.com/ipfs/go-ipfs-exchange-offline"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	format "github.com/ipfs/go-ipld-format"
+	"github.com/ipfs/go-merkledag"
+	dstest "github.com/ipfs/go-merkledag/test"
+	mh "github.com/multiformats/go-multihash"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	recovery "github.com/Wondertan/go-ipfs-recovery"
+	"github.com/Wondertan/go-ipfs-recovery/reedsolomon"
 )
 
 func TestEncodeDAG(t *testing.T) {
+	ctx := context.Background()
+
+	dag := dstest.Mock()
+	seq, err := recovery.EncodeDAG(ctx, dag, reedsolomon.NewEncoder(dag), newTestDAG(t, dag, 5), 2)
+	require.NoError(t, err)
+	_, ok := seq.(recovery.Node)
+	assert.True(t, ok)
+
+	for _, l := range seq.Links() {
+		nd, err := dag.Get(ctx, l.Cid)
+		require.NoError(t, err)
+		assert.Equal(t, 2, nd.(recovery.Node).Recoverability())
+	}
+
+	dag = dstest.Mock()
+	par, err := recovery.EncodeDAG(ctx, dag, reedsolomon.NewEncoder(dag), newTestDAG(t, dag, 5), 2, recovery.Concurrency(4))
+	require.NoError(t, err)
+	assert.True(t, seq.Cid().Equals(par.Cid()))
+	assert.Equal(t, seq.Links(), par.Links())
+}
+
+func TestEncodeDAGCbor(t *testing.T) {
+	ctx := context.Background()
+	dag := dstest.Mock()
+
+	ch1 := merkledag.NewRawNode([]byte("03243423423423"))
+	ch2 := merkledag.NewRawNode([]byte("123450"))
+	ch3 := merkledag.NewRawNode([]byte("1234509876"))
+	mid, err := cbor.WrapObject(map[string]interface{}{
+		"a": ch1.Cid(),
+		"b": []interface{}{ch2.Cid(), ch3.Cid()},
+	}, mh.SHA2_256, -1)
+	require.NoError(t, err)
+	root, err := cbor.WrapObject(map[string]interface{}{"m": mid.Cid()}, mh.SHA2_256, -1)
+	require.NoError(t, err)
+	require.NoError(t, dag.AddMany(ctx, []format.Node{root, mid, ch1, ch2, ch3}))
+
+	out, err := recovery.EncodeDAG(ctx, dag, reedsolomon.NewEncoder(dag), root, 2)
+	require.NoError(t, err)
+
+	// the root links the encoded middle Node rather than the removed original one
+	_, err = dag.Get(ctx, mid.Cid())
+	assert.Equal(t, format.ErrNotFound, err)
+
+	in := recovery.Unwrap(out.(recovery.Node))
+	require.Len(t, in.Links(), 1)
+	assert.True(t, in.Links()[0].Cid.Equals(out.Links()[0].Cid))
+
+	v, _, err := out.Resolve([]string{"m"})
+	require.NoError(t, err)
+	assert.True(t, in.Links()[0].Cid.Equals(v.(*format.Link).Cid))
+
+	mnd, err := dag.Get(ctx, v.(*format.Link).Cid)
+	require.NoError(t, err)
+	assert.Equal(t, 2, mnd.(recovery.Node).Recoverability())
+	assert.Equal(t, mid.RawData(), recovery.Unwrap(mnd.(recovery.Node)).RawData())
+
+	// re-encoding rebuilds the root with the new middle Node as well
+	rid, err := recovery.Reencode(ctx, dag, reedsolomon.NewEncoder(dag), out.Cid(), func(format.Node, int) recovery.Recoverability {
+		return 1
+	})
+	require.NoError(t, err)
+
+	rnd, err := dag.Get(ctx, rid)
+	require.NoError(t, err)
+	v, _, err = rnd.Resolve([]string{"m"})
+	require.NoError(t, err)
+
+	mnd, err = dag.Get(ctx, v.(*format.Link).Cid)
+	require.NoError(t, err)
+	assert.Equal(t, 1, mnd.(recovery.Node).Recoverability())
+}
+
+func TestEncodeDAGCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	dag := dstest.Mock()
+	_, err := recovery.EncodeDAG(ctx, dag, reedsolomon.NewEncoder(dag), newTestDAG(t, dag, 5), 2, recovery.Concurrency(4))
+	assert.Equal(t, context.Canceled, err)
+}
+
+// failingEncoder fails after the given amount of encodings.
+type failingEncoder struct {
+	recovery.Encoder
+	left int
+}
+
+func (e *failingEncoder) Encode(ctx context.Context, nd format.Node, r recovery.Recoverability) (recovery.Node, error) {
+	if e.left == 0 {
+		return nil, fmt.Errorf("failed")
+	}
+	e.left--
+
+	return e.Encoder.Encode(ctx, nd, r)
+}
+
+// getterOnly hides all the DAGService methods, but the NodeGetter ones.
+type getterOnly struct {
+	format.NodeGetter
+}
+
+func TestEncodeDAGGetterOnly(t *testing.T) {
+	ctx := context.Background()
+
+	dag := dstest.Mock()
+	e := &failingEncoder{Encoder: reedsolomon.NewEncoder(dag), left: 1}
+	_, err := recovery.EncodeDAG(ctx, getterOnly{dag}, e, newTestDAG(t, dag, 3), 2)
+	require.Error(t, err)
+	assert.Equal(t, 1, e.left) // nothing is encoded
+
+	cm := recovery.NewCidMap(datastore.NewMapDatastore())
+	_, err = recovery.EncodeDAG(ctx, getterOnly{dag}, reedsolomon.NewEncoder(dag), newTestDAG(t, dag, 3), 2,
+		recovery.KeepOriginals(cm))
+	require.NoError(t, err)
+}
+
+func TestEncodeDAGCheckpoint(t *testing.T) {
+	ctx := context.Background()
+
+	dag := dstest.Mock()
+	exp, err := recovery.EncodeDAG(ctx, dag, reedsolomon.NewEncoder(dag), newTestDAG(t, dag, 4), 2)
+	require.NoError(t, err)
+
+	ds := datastore.NewMapDatastore()
+	dag = dstest.Mock()
+	nd := newTestDAG(t, dag, 4)
+
+	e := &failingEncoder{Encoder: reedsolomon.NewEncoder(dag), left: 2}
+	_, err = recovery.EncodeDAG(ctx, dag, e, nd, 2, recovery.Checkpoint(ds))
+	require.Error(t, err)
+
+	// only the rest of subtrees and the root are encoded
+	e.left = 3
+	out, err := recovery.EncodeDAG(ctx, dag, e, nd, 2, recovery.Checkpoint(ds))
+	require.NoError(t, err)
+	assert.Equal(t, 0, e.left)
+	assert.True(t, exp.Cid().Equals(out.Cid()))
+
+	// everything is done already
+	out, err = recovery.EncodeDAG(ctx, dag, e, nd, 2, recovery.Checkpoint(ds))
+	require.NoError(t, err)
+	assert.True(t, exp.Cid().Equals(out.Cid()))
+}
+
+// failingDatastore fails writes once the given amount of them is done.
+type failingDatastore struct {
+	datastore.Datastore
+	left int
+}
+
+func (ds *failingDatastore) Put(k datastore.Key, v []byte) error {
+	if ds.left == 0 {
+		return fmt.Errorf("failed")
+	}
+	ds.left--
+
+	return ds.Datastore.Put(k, v)
+}
+
+func TestEncodeDAGCheckpointFailed(t *testing.T) {
+	ctx := context.Background()
+
+	dag := dstest.Mock()
+	exp, err := recovery.EncodeDAG(ctx, dag, reedsolomon.NewEncoder(dag), newTestDAG(t, dag, 4), 2)
+	require.NoError(t, err)
+
+	// the Node is encoded, but not checkpointed, so its original must still be there to resume
+	ds := &failingDatastore{Datastore: datastore.NewMapDatastore(), left: 2}
+	dag = dstest.Mock()
+	nd := newTestDAG(t, dag, 4)
+	_, err = recovery.EncodeDAG(ctx, dag, reedsolomon.NewEncoder(dag), nd, 2, recovery.Checkpoint(ds))
+	require.Error(t, err)
+
+	ds.left = -1
+	out, err := recovery.EncodeDAG(ctx, dag, reedsolomon.NewEncoder(dag), nd, 2, recovery.Checkpoint(ds))
+	require.NoError(t, err)
+	assert.True(t, exp.Cid().Equals(out.Cid()))
+}
+
+func TestEncodeDAGCheckpointMismatch(t *testing.T) {
+	ctx := context.Background()
+	ds := datastore.NewMapDatastore()
+	dag := dstest.Mock()
+	nd := newTestDAG(t, dag, 4)
+
+	e := &failingEncoder{Encoder: reedsolomon.NewEncoder(dag), left: 2}
+	_, err := recovery.EncodeDAG(ctx, dag, e, nd, 2, recovery.Checkpoint(ds))
+	require.Error(t, err)
+
+	e.left = -1
+	_, err = recovery.EncodeDAG(ctx, dag, e, nd, 1, recovery.Checkpoint(ds))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Recoverability")
+
+	_, err = recovery.EncodeDAG(ctx, dag, reedsolomon.NewStripedEncoder(dag, 0), nd, 2, recovery.Checkpoint(ds))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Encoder")
+}
+
+func TestEncodeDAGProgress(t *testing.T) {
+	ctx := context.Background()
+	dag := dstest.Mock()
+
+	var (
+		l      sync.Mutex
+		events = make(map[recovery.EncodeEventType]int)
+		parity uint64
+	)
+	sink := func(e recovery.EncodeEvent) {
+		l.Lock()
+		defer l.Unlock()
+		events[e.Type]++
+		parity += e.ParityBytes
+	}
+
+	out, err := recovery.EncodeDAG(ctx, dag, reedsolomon.NewEncoder(dag), newTestDAG(t, dag, 3), 2,
+		recovery.Concurrency(2), recovery.Progress(sink))
+	require.NoError(t, err)
+
+	assert.Equal(t, 13, events[recovery.NodeVisited])
+	assert.Equal(t, 4, events[recovery.NodeEncoded])
+	assert.Equal(t, 9, events[recovery.LeafSkipped])
+	assert.Zero(t, events[recovery.SubtreeSkipped])
+
+	var exp uint64
+	for _, id := range append([]cid.Cid{out.Cid()}, cids(out.Links())...) {
+		nd, err := dag.Get(ctx, id)
+		require.NoError(t, err)
+
+		for _, l := range nd.(recovery.Node).RecoveryLinks() {
+			exp += l.Size
+		}
+	}
+	assert.Equal(t, exp, parity)
+}
+
+func cids(ls []*format.Link) []cid.Cid {
+	ids := make([]cid.Cid, len(ls))
+	for i, l := range ls {
+		ids[i] = l.Cid
+	}
+	return ids
+}
+
+func TestEncodeDAGRecoverabilityPolicy(t *testing.T) {
+	ctx := context.Background()
+	dag := dstest.Mock()
+
+	out, err := recovery.EncodeDAG(ctx, dag, reedsolomon.NewEncoder(dag), newTestDAG(t, dag, 3), 2,
+		recovery.RecoverabilityPolicy(recovery.ByDepth(3, 1)))
+	require.NoError(t, err)
+	assert.Equal(t, 3, out.(recovery.Node).Recoverability())
+	for _, l := range out.Links() {
+		nd, err := dag.Get(ctx, l.Cid)
+		require.NoError(t, err)
+		assert.Equal(t, 1, nd.(recovery.Node).Recoverability())
+	}
+
+	// root is left unencoded, but still points to encoded children
+	dag = dstest.Mock()
+	out, err = recovery.EncodeDAG(ctx, dag, reedsolomon.NewEncoder(dag), newTestDAG(t, dag, 3), 2,
+		recovery.RecoverabilityPolicy(recovery.ByDepth(0, 2)))
+	require.NoError(t, err)
+	_, ok := out.(recovery.Node)
+	assert.False(t, ok)
+
+	_, err = dag.Get(ctx, out.Cid())
+	require.NoError(t, err)
+	for _, l := range out.Links() {
+		nd, err := dag.Get(ctx, l.Cid)
+		require.NoError(t, err)
+		assert.Equal(t, 2, nd.(recovery.Node).Recoverability())
+	}
+}
+
+func TestEncodeDAGAnchor(t *testing.T) {
+	ctx := context.Background()
+
+	ds := dsync.MutexWrap(datastore.NewMapDatastore())
+	bstore := blockstore.NewBlockstore(ds)
+	ex := offline.Exchange(bstore)
+	pi := recovery.NewParentIndex(ds)
+	dag := merkledag.NewDAGService(blockservice.New(bstore, ex))
+
+	var anchor recovery.EncodeEvent
+	sink := func(e recovery.EncodeEvent) {
+		if e.Type == recovery.AnchorEncoded {
+			anchor = e
+		}
+	}
+
+	// root is left unencoded, so it and its children are protected by the anchor only
+	nd := newTestDAG(t, dag, 3)
+	out, err := recovery.EncodeDAG(ctx, dag, reedsolomon.NewEncoder(dag), nd, 2,
+		recovery.RecoverabilityPolicy(recovery.ByDepth(0, 2)), recovery.Anchor(2, pi), recovery.Progress(sink))
+	require.NoError(t, err)
+	require.Equal(t, recovery.AnchorEncoded, anchor.Type)
+	assert.True(t, nd.Cid().Equals(anchor.Cid))
+	assert.Equal(t, 2, anchor.Recoverability)
+
+	an, err := dag.Get(ctx, anchor.Encoded)
+	require.NoError(t, err)
+	assert.Len(t, an.Links(), len(out.Links())+1)
+
+	// the anchor is found by the root without indexing the whole DAG
+	ids, err := pi.Get(out.Cid())
+	require.NoError(t, err)
+	assert.Equal(t, []cid.Cid{anchor.Encoded}, ids)
+
+	require.NoError(t, dag.Remove(ctx, out.Cid()))
+	require.NoError(t, dag.Remove(ctx, out.Links()[1].Cid))
+
+	ses := recovery.NewDagSession(ctx, reedsolomon.NewRecoverer(ctx, dag, recovery.Requested), ex, bstore,
+		recovery.WithParentIndex(pi))
+
+	got, err := ses.Get(ctx, out.Cid())
+	require.NoError(t, err)
+	assert.Equal(t, out.RawData(), got.RawData())
 
+	got, err = ses.Get(ctx, out.Links()[1].Cid)
+	require.NoError(t, err)
+	assert.Len(t, got.Links(), 3)
 }
@@ -0,0 +1,214 @@
+package entanglement
+
+import (
+	"fmt"
+
+	"github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-ipld-format"
+	"github.com/ipfs/go-merkledag"
+	"github.com/multiformats/go-varint"
+)
+
+// braid keeps data and parity blocks of the entangled Node and repairs missing ones.
+type braid struct {
+	lt *lattice
+
+	id   cid.Cid
+	ids  []cid.Cid
+	pos  map[cid.Cid][]int // identical blocks may take multiple positions
+	nds  []format.Node
+	data [][]byte
+	ps   [][]byte
+	size int
+}
+
+func newBraid(end *Node) (*braid, error) {
+	lt, err := newLattice(end.alpha, end.s, end.p, len(end.Links()))
+	if err != nil {
+		return nil, err
+	}
+	if len(end.RecoveryLinks()) != lt.parities() {
+		return nil, fmt.Errorf("entanglement: wrong amount of parity links")
+	}
+
+	ln := lt.n + lt.parities()
+	br := &braid{
+		lt:   lt,
+		id:   end.Cid(),
+		ids:  make([]cid.Cid, 0, ln),
+		pos:  make(map[cid.Cid][]int, ln),
+		nds:  make([]format.Node, ln),
+		data: make([][]byte, lt.n),
+		ps:   make([][]byte, lt.parities()),
+	}
+	if lt.n > 0 {
+		br.size = int(end.RecoveryLinks()[0].Size)
+	}
+
+	for i, l := range end.Links() {
+		br.add(l.Cid, i)
+	}
+	for i, l := range end.RecoveryLinks() {
+		br.add(l.Cid, lt.n+i)
+	}
+
+	return br, nil
+}
+
+func (br *braid) add(id cid.Cid, i int) {
+	if _, ok := br.pos[id]; !ok {
+		br.ids = append(br.ids, id)
+	}
+	br.pos[id] = append(br.pos[id], i)
+}
+
+// Parent returns CID of the entangled Node.
+func (br *braid) Parent() cid.Cid {
+	return br.id
+}
+
+// IDs returns all unique CIDs of data and parity blocks.
+func (br *braid) IDs() []cid.Cid {
+	return br.ids
+}
+
+// Fill puts given Node into the braid.
+func (br *braid) Fill(nd format.Node) {
+	for _, i := range br.pos[nd.Cid()] {
+		if br.nds[i] != nil {
+			continue
+		}
+
+		br.nds[i] = nd
+		if i < br.lt.n {
+			br.data[i] = make([]byte, br.size)
+			n := varint.PutUvarint(br.data[i], uint64(len(nd.RawData())))
+			copy(br.data[i][n:], nd.RawData())
+		} else {
+			br.ps[i-br.lt.n] = nd.RawData()
+		}
+	}
+}
+
+// Repair reconstructs all the blocks possible. Returns true if all data blocks are available.
+func (br *braid) Repair() bool {
+	return br.lt.repair(br.data, br.ps)
+}
+
+// Has checks whether the block is available either from filling or repairing.
+func (br *braid) Has(id cid.Cid) bool {
+	for _, i := range br.pos[id] {
+		if br.nds[i] != nil || (i < br.lt.n && br.data[i] != nil) || (i >= br.lt.n && br.ps[i-br.lt.n] != nil) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Get returns the Node by its id if it is available.
+func (br *braid) Get(id cid.Cid) (format.Node, error) {
+	is, ok := br.pos[id]
+	if !ok {
+		return nil, fmt.Errorf("entanglement: wrong child")
+	}
+
+	for _, i := range is {
+		if br.nds[i] != nil {
+			return br.nds[i], nil
+		}
+
+		var (
+			nd  format.Node
+			err error
+		)
+		switch {
+		case i < br.lt.n && br.data[i] != nil:
+			var data []byte
+			data, err = br.take(i)
+			if err != nil {
+				return nil, err
+			}
+			if !matches(id, data) {
+				return nil, fmt.Errorf("entanglement: repaired Node doesn't match %s", id)
+			}
+
+			b, _ := blocks.NewBlockWithCid(data, id)
+			nd, err = format.Decode(b)
+		case i >= br.lt.n && br.ps[i-br.lt.n] != nil:
+			if !matches(id, br.ps[i-br.lt.n]) {
+				return nil, fmt.Errorf("entanglement: repaired parity doesn't match %s", id)
+			}
+
+			nd, err = merkledag.NewRawNodeWPrefix(br.ps[i-br.lt.n], id.Prefix())
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		br.Fill(nd)
+		return nd, nil
+	}
+
+	return nil, fmt.Errorf("entanglement: not enough strands for available Nodes")
+}
+
+// take reads the data block at the given position without its length prefix.
+func (br *braid) take(i int) ([]byte, error) {
+	s, n, err := varint.FromUvarint(br.data[i])
+	if err != nil {
+		return nil, err
+	}
+	if s > uint64(len(br.data[i])-n) {
+		return nil, fmt.Errorf("entanglement: wrong block length")
+	}
+
+	return br.data[i][n : int(s)+n], nil
+}
+
+// matches checks whether the data hashes to the CID.
+func matches(id cid.Cid, data []byte) bool {
+	exp, err := id.Prefix().Sum(data)
+	return err == nil && exp.Equals(id)
+}
+
+// Data returns all the data Nodes possible.
+func (br *braid) Data() (nds []format.Node, err error) {
+	return br.collect(func(i int) bool { return i < br.lt.n })
+}
+
+// All returns all the Nodes possible.
+func (br *braid) All() (nds []format.Node, err error) {
+	return br.collect(func(int) bool { return true })
+}
+
+func (br *braid) collect(f func(int) bool) (nds []format.Node, err error) {
+	var ids []cid.Cid
+	for _, id := range br.ids {
+		for _, i := range br.pos[id] {
+			if f(i) {
+				ids = append(ids, id)
+				break
+			}
+		}
+	}
+
+	nds = make([]format.Node, 0, len(ids))
+	for _, id := range ids {
+		if !br.Has(id) {
+			continue
+		}
+
+		nd, err := br.Get(id)
+		if err != nil {
+			return nil, err
+		}
+
+		nds = append(nds, nd)
+	}
+
+	return nds, nil
+}
@@ -0,0 +1,58 @@
+package entanglement
+
+import (
+	"context"
+	"testing"
+
+	format "github.com/ipfs/go-ipld-format"
+	"github.com/ipfs/go-merkledag"
+	dstest "github.com/ipfs/go-merkledag/test"
+	"github.com/multiformats/go-varint"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBraidGetCorrupted(t *testing.T) {
+	ctx := context.Background()
+	dag := dstest.Mock()
+
+	prnt := merkledag.NodeWithData([]byte("1234567890"))
+	ch1 := merkledag.NodeWithData([]byte("03243423423423"))
+	ch2 := merkledag.NodeWithData([]byte("123450"))
+	prnt.AddNodeLink("link", ch1)
+	prnt.AddNodeLink("link", ch2)
+	dag.AddMany(ctx, []format.Node{prnt, ch1, ch2})
+
+	enc, err := Encode(ctx, dag, prnt, 1, 1, 1)
+	require.NoError(t, err)
+
+	br, err := newBraid(enc)
+	require.NoError(t, err)
+
+	// undecodable data is reported instead of being filled
+	br.data[0] = make([]byte, br.size)
+	n := varint.PutUvarint(br.data[0], 3)
+	copy(br.data[0][n:], []byte{0xff, 0xff, 0xff})
+	_, err = br.Get(ch1.Cid())
+	assert.Error(t, err)
+
+	// length prefix exceeding the block
+	br.data[0] = make([]byte, br.size)
+	varint.PutUvarint(br.data[0], uint64(br.size)*2)
+	_, err = br.Get(ch1.Cid())
+	assert.Error(t, err)
+
+	// decodable raw data not matching the CID
+	raw := merkledag.NewRawNode([]byte("03243423423423"))
+	br.pos[raw.Cid()] = []int{0}
+	br.data[0] = make([]byte, br.size)
+	n = varint.PutUvarint(br.data[0], uint64(len(raw.RawData())))
+	copy(br.data[0][n:], "13243423423423")
+	_, err = br.Get(raw.Cid())
+	assert.Error(t, err)
+
+	// corrupted parity
+	br.ps[0] = make([]byte, br.size)
+	_, err = br.Get(enc.RecoveryLinks()[0].Cid)
+	assert.Error(t, err)
+}
@@ -0,0 +1,65 @@
+package entanglement
+
+import (
+	"context"
+
+	"github.com/ipfs/go-ipld-format"
+	"github.com/ipfs/go-merkledag"
+	"github.com/multiformats/go-varint"
+)
+
+// Encode entangles children of the given IPLD Node into α strands promoting it to a recovery Node.
+// Use `s` and `p` to specify amount of horizontal and helical strands of the lattice.
+func Encode(ctx context.Context, dag format.DAGService, nd format.Node, alpha, s, p int) (*Node, error) {
+	end, err := entangle(ctx, dag, nd, alpha, s, p)
+	if err != nil {
+		return nil, err
+	}
+
+	return end, dag.Remove(ctx, nd.Cid()) // there is no need to keep original
+}
+
+// entangle is Encode leaving the original Node in place.
+func entangle(ctx context.Context, dag format.DAGService, nd format.Node, alpha, s, p int) (*Node, error) {
+	end, err := NewNode(nd, alpha, s, p)
+	if err != nil {
+		return nil, err
+	}
+
+	nds, size := make([]format.Node, len(end.Links())), 0
+	for i, l := range end.Links() {
+		nds[i], err = l.GetNode(ctx, dag)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(nds[i].RawData()) > size { // finding the largest child
+			size = len(nds[i].RawData())
+		}
+	}
+
+	size += varint.UvarintSize(uint64(size))
+	data := make([][]byte, len(nds))
+	for i, nd := range nds {
+		data[i] = make([]byte, size)
+		n := varint.PutUvarint(data[i], uint64(len(nd.RawData())))
+		copy(data[i][n:], nd.RawData())
+	}
+
+	lt, err := newLattice(alpha, s, p, len(nds))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, b := range lt.entangle(data) {
+		pnd := merkledag.NewRawNode(b)
+		err = dag.Add(ctx, pnd)
+		if err != nil {
+			return nil, err
+		}
+
+		end.AddParityNode(pnd)
+	}
+
+	return end, dag.Add(ctx, end)
+}
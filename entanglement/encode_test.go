@@ -0,0 +1,33 @@
+package entanglement
+
+import (
+	"context"
+	"testing"
+
+	format "github.com/ipfs/go-ipld-format"
+	"github.com/ipfs/go-merkledag"
+	dstest "github.com/ipfs/go-merkledag/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncode(t *testing.T) {
+	ctx := context.Background()
+	dag := dstest.Mock()
+
+	in := merkledag.NodeWithData([]byte("1234567890"))
+	in2 := merkledag.NodeWithData([]byte("0987654321"))
+	in3 := merkledag.NodeWithData([]byte("1234509876"))
+	in.AddNodeLink("link", in2)
+	in.AddNodeLink("link", in3)
+	dag.AddMany(ctx, []format.Node{in, in2, in3})
+
+	nd, err := Encode(ctx, dag, in, 3, 2, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 3, nd.Recoverability())
+	assert.Len(t, nd.RecoveryLinks(), 6)
+	for _, r := range nd.RecoveryLinks() {
+		_, err := dag.Get(ctx, r.Cid)
+		assert.NoError(t, err)
+	}
+}
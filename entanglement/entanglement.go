@@ -0,0 +1,51 @@
+package entanglement
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-ipld-format"
+	logging "github.com/ipfs/go-log/v2"
+
+	"github.com/Wondertan/go-ipfs-recovery"
+)
+
+var log = logging.Logger("recovery")
+
+// Custom codec for Alpha Entanglement recovery Nodes.
+const Codec uint64 = 0x701 // random number // TODO Register in IPFS codec table.
+
+func init() {
+	// register global decoder
+	format.Register(Codec, DecodeNode)
+
+	// register codec
+	cid.Codecs["recovery-entanglement"] = Codec
+	cid.CodecToStr[Codec] = "recovery-entanglement"
+}
+
+type entanglement struct {
+	dag  format.DAGService
+	s, p int
+}
+
+// NewEncoder creates new Alpha Entanglement Encoder with `s` horizontal strands and `p` helical strands.
+// Recoverability passed on encoding is used as α - amount of strands every data Node is entangled with.
+func NewEncoder(dag format.DAGService, s, p int) recovery.Encoder {
+	return &entanglement{dag: dag, s: s, p: p}
+}
+
+// Encode entangles the Node leaving the original in place, so the caller removes it once it is safe to.
+func (e *entanglement) Encode(ctx context.Context, nd format.Node, r recovery.Recoverability) (recovery.Node, error) {
+	end, ok := nd.(recovery.Node)
+	if ok {
+		return end, nil
+	}
+
+	return entangle(ctx, e.dag, nd, r, e.s, e.p)
+}
+
+func (e *entanglement) String() string {
+	return fmt.Sprintf("entanglement/%d/%d", e.s, e.p)
+}
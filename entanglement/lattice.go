@@ -0,0 +1,253 @@
+package entanglement
+
+import (
+	"fmt"
+)
+
+// Strand classes of the lattice. Every data block is entangled with one strand of each class up to α.
+const (
+	Horizontal = iota
+	RightHanded
+	LeftHanded
+)
+
+// MaxAlpha is the maximum amount of strand classes supported.
+const MaxAlpha = 3
+
+// lattice describes geometry of Alpha Entanglement parity lattice.
+// Data blocks are placed column by column into `s` rows, where each block is entangled with the next one
+// in the strand via a parity block: p(i) = d(i) XOR p(prev(i)).
+// Helical strands wrap from bottom to top row or vice versa with a `p` columns period.
+type lattice struct {
+	alpha, s, p, n int
+}
+
+func newLattice(alpha, s, p, n int) (*lattice, error) {
+	err := validate(alpha, s, p)
+	if err != nil {
+		return nil, err
+	}
+
+	return &lattice{alpha: alpha, s: s, p: p, n: n}, nil
+}
+
+func validate(alpha, s, p int) error {
+	if alpha < 1 || alpha > MaxAlpha {
+		return fmt.Errorf("entanglement: alpha must be in range [1, %d]", MaxAlpha)
+	}
+	if s < 1 {
+		return fmt.Errorf("entanglement: s must be positive")
+	}
+	if p < s {
+		return fmt.Errorf("entanglement: p must not be less than s")
+	}
+
+	return nil
+}
+
+// next returns the position of the data block following the given one in the strand of the class,
+// or -1 if there is none.
+func (l *lattice) next(class, i int) int {
+	c, r := i/l.s, i%l.s
+
+	var j int
+	switch class {
+	case Horizontal:
+		j = (c+1)*l.s + r
+	case RightHanded:
+		if r < l.s-1 {
+			j = (c+1)*l.s + r + 1
+		} else {
+			j = (c + 1 + l.p - l.s) * l.s
+		}
+	case LeftHanded:
+		if r > 0 {
+			j = (c+1)*l.s + r - 1
+		} else {
+			j = (c+1+l.p-l.s)*l.s + l.s - 1
+		}
+	}
+
+	if j >= l.n {
+		return -1
+	}
+
+	return j
+}
+
+// prev returns the position of the data block preceding the given one in the strand of the class,
+// or -1 if the block starts the strand.
+func (l *lattice) prev(class, i int) int {
+	c, r := i/l.s, i%l.s
+
+	var j int
+	switch class {
+	case Horizontal:
+		j = (c-1)*l.s + r
+	case RightHanded:
+		if r > 0 {
+			j = (c-1)*l.s + r - 1
+		} else {
+			j = (c-1-l.p+l.s)*l.s + l.s - 1
+		}
+	case LeftHanded:
+		if r < l.s-1 {
+			j = (c-1)*l.s + r + 1
+		} else {
+			j = (c - 1 - l.p + l.s) * l.s
+		}
+	}
+
+	if j < 0 {
+		return -1
+	}
+
+	return j
+}
+
+// parities returns the total amount of parity blocks in the lattice.
+func (l *lattice) parities() int {
+	return l.alpha * l.n
+}
+
+// parity returns index of the parity block produced by the data block at the given position in the strand of the class.
+func (l *lattice) parity(class, i int) int {
+	return class*l.n + i
+}
+
+// entangle computes parity blocks for the given equally sized data blocks.
+func (l *lattice) entangle(data [][]byte) [][]byte {
+	ps := make([][]byte, l.parities())
+	for class := 0; class < l.alpha; class++ {
+		for i := range data { // previous block is always placed before, so it is already computed
+			var pp []byte
+			if j := l.prev(class, i); j != -1 {
+				pp = ps[l.parity(class, j)]
+			}
+
+			ps[l.parity(class, i)] = xor(data[i], pp)
+		}
+	}
+
+	return ps
+}
+
+// repair reconstructs missing(nil) data and parity blocks in place, until no more progress can be made.
+// Returns true if all the data blocks are available.
+func (l *lattice) repair(data, ps [][]byte) bool {
+	for progress := true; progress; {
+		progress = false
+		for class := 0; class < l.alpha; class++ {
+			for i := range data {
+				pi := l.parity(class, i)
+
+				// prev parity is implicitly zero at the beginning of the strand.
+				pp, prvOk := []byte(nil), true
+				if j := l.prev(class, i); j != -1 {
+					pp = ps[l.parity(class, j)]
+					prvOk = pp != nil
+				}
+
+				// p(i) = p(next(i)) XOR d(next(i))
+				if ps[pi] == nil {
+					if j := l.next(class, i); j != -1 && data[j] != nil && ps[l.parity(class, j)] != nil {
+						ps[pi] = xor(ps[l.parity(class, j)], data[j])
+						progress = true
+					}
+				}
+
+				if !prvOk {
+					continue
+				}
+
+				// d(i) = p(i) XOR p(prev(i))
+				if data[i] == nil && ps[pi] != nil {
+					data[i] = xor(ps[pi], pp)
+					progress = true
+				}
+
+				// p(i) = d(i) XOR p(prev(i))
+				if ps[pi] == nil && data[i] != nil {
+					ps[pi] = xor(data[i], pp)
+					progress = true
+				}
+			}
+		}
+	}
+
+	for _, d := range data {
+		if d == nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// margin estimates how many blocks can be lost additionally around any data block preserving its recoverability, given
+// positions of missing data and parity blocks. Every available strand of the data block tolerates one loss, while loss
+// of the block itself consumes one of them. Blocks repairable only through other strands don't make the margin
+// negative, but ones which can't be repaired at all do, by their amount.
+func (l *lattice) margin(data, parity []int) int {
+	ds, ps := make([][]byte, l.n), make([][]byte, l.parities())
+	for i := range ds {
+		ds[i] = []byte{}
+	}
+	for i := range ps {
+		ps[i] = []byte{}
+	}
+	for _, i := range data {
+		if i >= 0 && i < l.n {
+			ds[i] = nil
+		}
+	}
+	for _, i := range parity {
+		if i >= 0 && i < len(ps) {
+			ps[i] = nil
+		}
+	}
+
+	m := l.alpha
+	for i := range ds {
+		t := l.alpha
+		if ds[i] == nil {
+			t--
+		}
+		for class := 0; class < l.alpha; class++ {
+			if ps[l.parity(class, i)] == nil {
+				t--
+			} else if j := l.prev(class, i); j != -1 && ps[l.parity(class, j)] == nil {
+				t--
+			}
+		}
+
+		if t < m {
+			m = t
+		}
+	}
+	if m < 0 {
+		m = 0
+	}
+
+	// presence is simulated with empty blocks
+	if !l.repair(ds, ps) {
+		m = 0
+		for _, d := range ds {
+			if d == nil {
+				m--
+			}
+		}
+	}
+
+	return m
+}
+
+// xor returns a new block with a XOR b, where nil b stands for zero block.
+func xor(a, b []byte) []byte {
+	out := make([]byte, len(a))
+	copy(out, a)
+	for i := range b {
+		out[i] ^= b[i]
+	}
+	return out
+}
@@ -0,0 +1,97 @@
+package entanglement
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatticeNextPrev(t *testing.T) {
+	lt, err := newLattice(3, 5, 7, 100)
+	require.NoError(t, err)
+
+	for class := 0; class < lt.alpha; class++ {
+		for i := 0; i < lt.n; i++ {
+			if j := lt.next(class, i); j != -1 {
+				assert.Greater(t, j, i)
+				assert.Equal(t, i, lt.prev(class, j))
+			}
+			if j := lt.prev(class, i); j != -1 {
+				assert.Less(t, j, i)
+				assert.Equal(t, i, lt.next(class, j))
+			}
+		}
+	}
+}
+
+func TestLatticeValidate(t *testing.T) {
+	_, err := newLattice(0, 2, 2, 1)
+	assert.Error(t, err)
+	_, err = newLattice(MaxAlpha+1, 2, 2, 1)
+	assert.Error(t, err)
+	_, err = newLattice(1, 0, 2, 1)
+	assert.Error(t, err)
+	_, err = newLattice(1, 3, 2, 1)
+	assert.Error(t, err)
+}
+
+func TestLatticeRepair(t *testing.T) {
+	lt, err := newLattice(3, 3, 5, 30)
+	require.NoError(t, err)
+
+	data := make([][]byte, lt.n)
+	for i := range data {
+		data[i] = make([]byte, 16)
+		rand.Read(data[i])
+	}
+	ps := lt.entangle(data)
+
+	ldata, lps := make([][]byte, len(data)), make([][]byte, len(ps))
+	copy(ldata, data)
+	copy(lps, ps)
+	for _, i := range []int{0, 1, 2, 7, 8, 15, 29} {
+		ldata[i] = nil
+	}
+	for _, i := range []int{0, 8, 30, 45, 60, 89} {
+		lps[i] = nil
+	}
+
+	assert.True(t, lt.repair(ldata, lps))
+	for i := range data {
+		assert.True(t, bytes.Equal(data[i], ldata[i]))
+	}
+}
+
+func TestLatticeRepairFails(t *testing.T) {
+	lt, err := newLattice(1, 2, 2, 4)
+	require.NoError(t, err)
+
+	data := make([][]byte, lt.n)
+	for i := range data {
+		data[i] = []byte{byte(i)}
+	}
+	ps := lt.entangle(data)
+
+	data[0], ps[0], ps[2] = nil, nil, nil
+	assert.False(t, lt.repair(data, ps))
+}
+
+func TestLatticeMargin(t *testing.T) {
+	lt, err := newLattice(2, 2, 2, 10)
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, lt.margin(nil, nil))
+	assert.Equal(t, 1, lt.margin([]int{4}, nil))
+	// losses in different strands around the same block are repaired through other ones
+	assert.Equal(t, 0, lt.margin(nil, []int{lt.parity(Horizontal, 4), lt.parity(RightHanded, 4), 7}))
+
+	// the block with all its strands lost is beyond repair
+	all := make([]int, lt.parities())
+	for i := range all {
+		all[i] = i
+	}
+	assert.Equal(t, -1, lt.margin([]int{4}, all))
+}
@@ -0,0 +1,287 @@
+package entanglement
+
+import (
+	"fmt"
+
+	"github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-ipld-format"
+	"github.com/ipfs/go-merkledag"
+
+	"github.com/Wondertan/go-ipfs-recovery"
+	epb "github.com/Wondertan/go-ipfs-recovery/entanglement/pb"
+)
+
+// Node is a recovery Node based on Alpha Entanglement coding.
+type Node struct {
+	*merkledag.ProtoNode
+
+	alpha, s, p int
+
+	parity  []*format.Link
+	cache   []byte
+	cid     cid.Cid
+	builder cid.Builder
+	prefix  cid.Prefix // of the entangled ProtoNode CID, restored on decoding
+}
+
+// NewNode creates new Alpha Entanglement Node with the given lattice parameters.
+func NewNode(nd format.Node, alpha, s, p int) (*Node, error) {
+	pnd, ok := nd.(*merkledag.ProtoNode)
+	if !ok {
+		return nil, fmt.Errorf("entanglement: Node must be proto")
+	}
+
+	err := validate(alpha, s, p)
+	if err != nil {
+		return nil, err
+	}
+
+	end := &Node{ProtoNode: pnd.Copy().(*merkledag.ProtoNode), alpha: alpha, s: s, p: p, prefix: pnd.Cid().Prefix()}
+	end.SetCidBuilder(pnd.CidBuilder())
+	return end, nil
+}
+
+func (n *Node) Proto() *merkledag.ProtoNode {
+	return n.ProtoNode
+}
+
+// Recoverability of the entangled Node is equal to the amount of strands every data Node is entangled with.
+func (n *Node) Recoverability() recovery.Recoverability {
+	return n.alpha
+}
+
+func (n *Node) RecoveryLinks() []*format.Link {
+	return n.parity
+}
+
+// Margin estimates the amount of Nodes which can be lost additionally around any data Node preserving its
+// recoverability, given positions of missing children and Redundant Nodes. Unlike with MDS codes, losses far apart
+// in the lattice don't affect each other.
+func (n *Node) Margin(data, parity []int) int {
+	lt, err := newLattice(n.alpha, n.s, n.p, len(n.Links()))
+	if err != nil {
+		return -len(data)
+	}
+
+	return lt.margin(data, parity)
+}
+
+// Params returns lattice parameters of the Node.
+func (n *Node) Params() (alpha, s, p int) {
+	return n.alpha, n.s, n.p
+}
+
+func (n *Node) AddParityNode(nd format.Node) {
+	if nd == nil {
+		return
+	}
+
+	n.cache = nil
+	n.parity = append(n.parity, &format.Link{
+		Size: uint64(len(nd.RawData())),
+		Cid:  nd.Cid(),
+	})
+}
+
+func (n *Node) RawData() []byte {
+	if n.cache != nil {
+		return n.cache
+	}
+
+	var err error
+	n.cache, err = MarshalNode(n)
+	if err != nil {
+		panic(fmt.Sprintf("can't marshal Node: %s", err))
+	}
+
+	return n.cache
+}
+
+func (n *Node) Cid() cid.Cid {
+	if n.cache != nil && n.cid.Defined() {
+		return n.cid
+	}
+
+	var err error
+	n.cid, err = n.CidBuilder().Sum(n.RawData())
+	if err != nil {
+		panic(fmt.Sprintf("can't form CID: %s", err))
+	}
+
+	return n.cid
+}
+
+func (n *Node) String() string {
+	return n.Cid().String()
+}
+
+func (n *Node) Copy() format.Node {
+	nd := new(Node)
+	nd.ProtoNode = n.ProtoNode.Copy().(*merkledag.ProtoNode)
+	nd.builder, nd.prefix = n.builder, n.prefix
+	nd.alpha, nd.s, nd.p = n.alpha, n.s, n.p
+	l := len(n.parity)
+	if l > 0 {
+		nd.parity = make([]*format.Link, l)
+		for i, r := range n.parity {
+			nd.parity[i] = &format.Link{
+				Name: r.Name,
+				Size: r.Size,
+				Cid:  r.Cid,
+			}
+		}
+	}
+
+	return nd
+}
+
+func (n *Node) Stat() (*format.NodeStat, error) {
+	l := len(n.RawData())
+	cumSize, err := n.Size()
+	if err != nil {
+		return nil, err
+	}
+
+	return &format.NodeStat{
+		Hash:           n.Cid().String(),
+		NumLinks:       len(n.Links()),
+		BlockSize:      l,
+		DataSize:       len(n.Data()),
+		CumulativeSize: int(cumSize),
+	}, nil
+}
+
+func (n *Node) Size() (uint64, error) {
+	s := uint64(len(n.RawData()))
+	for _, l := range n.Links() {
+		s += l.Size
+	}
+	for _, l := range n.parity {
+		s += l.Size
+	}
+	return s, nil
+}
+
+func MarshalNode(n *Node) ([]byte, error) {
+	var err error
+	pb := &epb.PBNode{
+		Alpha: uint32(n.alpha),
+		S:     uint32(n.s),
+		P:     uint32(n.p),
+	}
+	if n.prefix != (cid.Prefix{}) {
+		pb.Prefix = n.prefix.Bytes()
+	}
+	pb.Proto, err = n.ProtoNode.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	// order of parity links is meaningful, so they are not sorted.
+	l := len(n.parity)
+	if l > 0 {
+		pb.Parity = make([]*epb.PBLink, l)
+		for i, r := range n.parity {
+			pb.Parity[i] = &epb.PBLink{
+				Name:  r.Name,
+				Size_: r.Size,
+				Hash:  r.Cid.Bytes(),
+			}
+		}
+	}
+
+	return pb.Marshal()
+}
+
+func UnmarshalNode(data []byte) (*Node, error) {
+	pb := &epb.PBNode{}
+	err := pb.Unmarshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	nd := &Node{alpha: int(pb.Alpha), s: int(pb.S), p: int(pb.P)}
+	err = validate(nd.alpha, nd.s, nd.p)
+	if err != nil {
+		return nil, err
+	}
+
+	nd.ProtoNode, err = merkledag.DecodeProtobuf(pb.Proto)
+	if err != nil {
+		return nil, err
+	}
+	if pb.Prefix != nil {
+		nd.prefix, err = cid.PrefixFromBytes(pb.Prefix)
+		if err != nil {
+			return nil, err
+		}
+
+		nd.ProtoNode.SetCidBuilder(nd.prefix)
+	}
+
+	l := len(pb.Parity)
+	if l > 0 {
+		nd.parity = make([]*format.Link, l)
+		for i, r := range pb.Parity {
+			nd.parity[i] = &format.Link{
+				Name: r.Name,
+				Size: r.Size_,
+			}
+
+			nd.parity[i].Cid, err = cid.Cast(r.Hash)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return nd, nil
+}
+
+func DecodeNode(b blocks.Block) (format.Node, error) {
+	id := b.Cid()
+	if id.Prefix().Codec != Codec {
+		return nil, fmt.Errorf("can only decode entangled node")
+	}
+
+	nd, err := UnmarshalNode(b.RawData())
+	if err != nil {
+		return nil, err
+	}
+
+	nd.cid = b.Cid()
+	nd.SetCidBuilder(b.Cid().Prefix())
+	return nd, nil
+}
+
+// Shadowed methods to reset caching.
+//
+func (n *Node) AddRawLink(name string, l *format.Link) error {
+	n.cache = nil
+	return n.ProtoNode.AddRawLink(name, l)
+}
+
+func (n *Node) AddNodeLink(name string, that format.Node) error {
+	n.cache = nil
+	return n.ProtoNode.AddNodeLink(name, that)
+}
+
+func (n *Node) RemoveNodeLink(name string) error {
+	n.cache = nil
+	return n.ProtoNode.RemoveNodeLink(name)
+}
+
+func (n *Node) SetData(d []byte) {
+	n.cache = nil
+	n.ProtoNode.SetData(d)
+}
+
+func (n *Node) SetCidBuilder(b cid.Builder) {
+	n.builder = b.WithCodec(Codec)
+	n.cid = cid.Undef
+}
+
+func (n *Node) CidBuilder() cid.Builder {
+	return n.builder
+}
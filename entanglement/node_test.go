@@ -0,0 +1,65 @@
+package entanglement
+
+import (
+	"testing"
+
+	"github.com/ipfs/go-ipld-format"
+	"github.com/ipfs/go-merkledag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNodeMarshalUnmarshal(t *testing.T) {
+	pdata := []byte("1234567890")
+	in, err := NewNode(merkledag.NodeWithData(pdata), 2, 3, 4)
+	require.NoError(t, err)
+	assert.Equal(t, pdata, in.Data())
+
+	in.AddParityNode(merkledag.NewRawNode([]byte("12345")))
+	in.AddParityNode(merkledag.NewRawNode([]byte("00000")))
+
+	data, err := MarshalNode(in)
+	require.NoError(t, err)
+
+	out, err := UnmarshalNode(data)
+	require.NoError(t, err)
+
+	out.SetCidBuilder(in.CidBuilder())
+	assert.True(t, in.Cid().Equals(out.Cid()))
+	assert.Equal(t, in.RecoveryLinks(), out.RecoveryLinks())
+	assert.Equal(t, pdata, out.Data())
+
+	alpha, s, p := out.Params()
+	assert.Equal(t, 2, alpha)
+	assert.Equal(t, 3, s)
+	assert.Equal(t, 4, p)
+}
+
+func TestNodeWrongParams(t *testing.T) {
+	_, err := NewNode(merkledag.NodeWithData([]byte("1234567890")), 4, 3, 4)
+	assert.Error(t, err)
+}
+
+func TestNodeDecode(t *testing.T) {
+	in, err := NewNode(merkledag.NodeWithData([]byte("1234567890")), 1, 1, 1)
+	require.NoError(t, err)
+	require.Equal(t, Codec, in.Cid().Type())
+
+	out, err := format.Decode(in)
+	require.NoError(t, err)
+	assert.True(t, in.Cid().Equals(out.Cid()))
+}
+
+func TestNode_Copy(t *testing.T) {
+	nd, err := NewNode(merkledag.NodeWithData([]byte("1234567890")), 1, 1, 1)
+	require.NoError(t, err)
+
+	nd.AddParityNode(merkledag.NewRawNode([]byte("12345")))
+
+	cp := nd.Copy()
+	nd.SetData([]byte{})
+
+	assert.NotNil(t, cp.(*Node).RecoveryLinks())
+	assert.NotNil(t, cp.(*Node).Data())
+	assert.Equal(t, nd.Recoverability(), cp.(*Node).Recoverability())
+}
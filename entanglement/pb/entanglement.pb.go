@@ -0,0 +1,961 @@
+// Code generated by protoc-gen-gogo. DO NOT EDIT.
+// source: entanglement/pb/entanglement.proto
+
+package entanglement_pb
+
+import (
+	bytes "bytes"
+	fmt "fmt"
+	proto "github.com/gogo/protobuf/proto"
+	io "io"
+	math "math"
+	math_bits "math/bits"
+	reflect "reflect"
+	strings "strings"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.GoGoProtoPackageIsVersion3 // please upgrade the proto package
+
+type PBNode struct {
+	Proto  []byte    `protobuf:"bytes,1,opt,name=proto,proto3" json:"proto,omitempty"`
+	Parity []*PBLink `protobuf:"bytes,2,rep,name=parity,proto3" json:"parity,omitempty"`
+	Alpha  uint32    `protobuf:"varint,3,opt,name=alpha,proto3" json:"alpha,omitempty"`
+	S      uint32    `protobuf:"varint,4,opt,name=s,proto3" json:"s,omitempty"`
+	P      uint32    `protobuf:"varint,5,opt,name=p,proto3" json:"p,omitempty"`
+	Prefix []byte    `protobuf:"bytes,6,opt,name=prefix,proto3" json:"prefix,omitempty"`
+}
+
+func (m *PBNode) Reset()      { *m = PBNode{} }
+func (*PBNode) ProtoMessage() {}
+func (*PBNode) Descriptor() ([]byte, []int) {
+	return fileDescriptor_5f5cc0c2bd38c1c6, []int{0}
+}
+func (m *PBNode) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *PBNode) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_PBNode.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *PBNode) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PBNode.Merge(m, src)
+}
+func (m *PBNode) XXX_Size() int {
+	return m.Size()
+}
+func (m *PBNode) XXX_DiscardUnknown() {
+	xxx_messageInfo_PBNode.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PBNode proto.InternalMessageInfo
+
+func (m *PBNode) GetProto() []byte {
+	if m != nil {
+		return m.Proto
+	}
+	return nil
+}
+
+func (m *PBNode) GetParity() []*PBLink {
+	if m != nil {
+		return m.Parity
+	}
+	return nil
+}
+
+func (m *PBNode) GetAlpha() uint32 {
+	if m != nil {
+		return m.Alpha
+	}
+	return 0
+}
+
+func (m *PBNode) GetS() uint32 {
+	if m != nil {
+		return m.S
+	}
+	return 0
+}
+
+func (m *PBNode) GetP() uint32 {
+	if m != nil {
+		return m.P
+	}
+	return 0
+}
+
+func (m *PBNode) GetPrefix() []byte {
+	if m != nil {
+		return m.Prefix
+	}
+	return nil
+}
+
+type PBLink struct {
+	Hash  []byte `protobuf:"bytes,1,opt,name=Hash,proto3" json:"Hash,omitempty"`
+	Name  string `protobuf:"bytes,2,opt,name=Name,proto3" json:"Name,omitempty"`
+	Size_ uint64 `protobuf:"varint,3,opt,name=Size,proto3" json:"Size,omitempty"`
+}
+
+func (m *PBLink) Reset()      { *m = PBLink{} }
+func (*PBLink) ProtoMessage() {}
+func (*PBLink) Descriptor() ([]byte, []int) {
+	return fileDescriptor_5f5cc0c2bd38c1c6, []int{1}
+}
+func (m *PBLink) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *PBLink) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_PBLink.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *PBLink) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_PBLink.Merge(m, src)
+}
+func (m *PBLink) XXX_Size() int {
+	return m.Size()
+}
+func (m *PBLink) XXX_DiscardUnknown() {
+	xxx_messageInfo_PBLink.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_PBLink proto.InternalMessageInfo
+
+func (m *PBLink) GetHash() []byte {
+	if m != nil {
+		return m.Hash
+	}
+	return nil
+}
+
+func (m *PBLink) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *PBLink) GetSize_() uint64 {
+	if m != nil {
+		return m.Size_
+	}
+	return 0
+}
+
+func init() {
+	proto.RegisterType((*PBNode)(nil), "entanglement.pb.PBNode")
+	proto.RegisterType((*PBLink)(nil), "entanglement.pb.PBLink")
+}
+
+func init() {
+	proto.RegisterFile("entanglement/pb/entanglement.proto", fileDescriptor_5f5cc0c2bd38c1c6)
+}
+
+var fileDescriptor_5f5cc0c2bd38c1c6 = []byte{
+	// 264 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x5c, 0x8f, 0x31, 0x4e, 0xc3, 0x30,
+	0x14, 0x86, 0xfd, 0xda, 0xd4, 0x12, 0xa6, 0x08, 0xc9, 0x42, 0xe0, 0xe9, 0x29, 0xca, 0x94, 0x29,
+	0x91, 0x80, 0x13, 0x44, 0x0c, 0x0c, 0xa8, 0xaa, 0xcc, 0x09, 0x1c, 0x61, 0x68, 0x44, 0x9b, 0x58,
+	0x49, 0x06, 0x60, 0xe2, 0x08, 0xac, 0xdc, 0x80, 0xa3, 0x30, 0x66, 0xec, 0x48, 0x9c, 0x85, 0x31,
+	0x47, 0x40, 0x71, 0x32, 0x00, 0xdb, 0xff, 0x7d, 0x7a, 0xfa, 0x7f, 0x9b, 0x05, 0x3a, 0xaf, 0x55,
+	0xfe, 0xb0, 0xd5, 0x3b, 0x9d, 0xd7, 0xb1, 0x49, 0xe3, 0xdf, 0x1c, 0x99, 0xb2, 0xa8, 0x0b, 0x7e,
+	0xfc, 0xd7, 0xa5, 0xc1, 0x3b, 0x30, 0xba, 0x4e, 0x56, 0xc5, 0x9d, 0xe6, 0x27, 0x6c, 0xe1, 0x8e,
+	0x04, 0xf8, 0x10, 0x2e, 0xe5, 0x08, 0x3c, 0x66, 0xd4, 0xa8, 0x32, 0xab, 0x9f, 0xc5, 0xcc, 0x9f,
+	0x87, 0x87, 0xe7, 0x67, 0xd1, 0xbf, 0x8a, 0x68, 0x9d, 0xdc, 0x64, 0xf9, 0xa3, 0x9c, 0xce, 0x86,
+	0x1a, 0xb5, 0x35, 0x1b, 0x25, 0xe6, 0x3e, 0x84, 0x47, 0x72, 0x04, 0xbe, 0x64, 0x50, 0x09, 0xcf,
+	0x19, 0xa8, 0x06, 0x32, 0x62, 0x31, 0x92, 0xe1, 0xa7, 0x8c, 0x9a, 0x52, 0xdf, 0x67, 0x4f, 0x82,
+	0xba, 0xe5, 0x89, 0x82, 0x2b, 0x46, 0xc7, 0x6e, 0xce, 0x99, 0x77, 0xad, 0xaa, 0xcd, 0xf4, 0x32,
+	0x97, 0x07, 0xb7, 0x52, 0x3b, 0x2d, 0x66, 0x3e, 0x84, 0x07, 0xd2, 0xe5, 0xc1, 0xdd, 0x66, 0x2f,
+	0xda, 0x4d, 0x7b, 0xd2, 0xe5, 0xe4, 0xb2, 0x69, 0x91, 0xec, 0x5b, 0x24, 0x7d, 0x8b, 0xf0, 0x6a,
+	0x11, 0x3e, 0x2c, 0xc2, 0xa7, 0x45, 0x68, 0x2c, 0xc2, 0x97, 0x45, 0xf8, 0xb6, 0x48, 0x7a, 0x8b,
+	0xf0, 0xd6, 0x21, 0x69, 0x3a, 0x24, 0xfb, 0x0e, 0x49, 0x4a, 0xdd, 0xef, 0x2f, 0x7e, 0x06, 0x00,
+	0xf9, 0x66, 0xb9, 0xcd, 0x55, 0x01, 0x00, 0x00,
+}
+
+func (this *PBNode) Equal(that interface{}) bool {
+	if that == nil {
+		return this == nil
+	}
+
+	that1, ok := that.(*PBNode)
+	if !ok {
+		that2, ok := that.(PBNode)
+		if ok {
+			that1 = &that2
+		} else {
+			return false
+		}
+	}
+	if that1 == nil {
+		return this == nil
+	} else if this == nil {
+		return false
+	}
+	if !bytes.Equal(this.Proto, that1.Proto) {
+		return false
+	}
+	if len(this.Parity) != len(that1.Parity) {
+		return false
+	}
+	for i := range this.Parity {
+		if !this.Parity[i].Equal(that1.Parity[i]) {
+			return false
+		}
+	}
+	if this.Alpha != that1.Alpha {
+		return false
+	}
+	if this.S != that1.S {
+		return false
+	}
+	if this.P != that1.P {
+		return false
+	}
+	if !bytes.Equal(this.Prefix, that1.Prefix) {
+		return false
+	}
+	return true
+}
+func (this *PBLink) Equal(that interface{}) bool {
+	if that == nil {
+		return this == nil
+	}
+
+	that1, ok := that.(*PBLink)
+	if !ok {
+		that2, ok := that.(PBLink)
+		if ok {
+			that1 = &that2
+		} else {
+			return false
+		}
+	}
+	if that1 == nil {
+		return this == nil
+	} else if this == nil {
+		return false
+	}
+	if !bytes.Equal(this.Hash, that1.Hash) {
+		return false
+	}
+	if this.Name != that1.Name {
+		return false
+	}
+	if this.Size_ != that1.Size_ {
+		return false
+	}
+	return true
+}
+func (this *PBNode) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 10)
+	s = append(s, "&entanglement_pb.PBNode{")
+	s = append(s, "Proto: "+fmt.Sprintf("%#v", this.Proto)+",\n")
+	if this.Parity != nil {
+		s = append(s, "Parity: "+fmt.Sprintf("%#v", this.Parity)+",\n")
+	}
+	s = append(s, "Alpha: "+fmt.Sprintf("%#v", this.Alpha)+",\n")
+	s = append(s, "S: "+fmt.Sprintf("%#v", this.S)+",\n")
+	s = append(s, "P: "+fmt.Sprintf("%#v", this.P)+",\n")
+	s = append(s, "Prefix: "+fmt.Sprintf("%#v", this.Prefix)+",\n")
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func (this *PBLink) GoString() string {
+	if this == nil {
+		return "nil"
+	}
+	s := make([]string, 0, 7)
+	s = append(s, "&entanglement_pb.PBLink{")
+	s = append(s, "Hash: "+fmt.Sprintf("%#v", this.Hash)+",\n")
+	s = append(s, "Name: "+fmt.Sprintf("%#v", this.Name)+",\n")
+	s = append(s, "Size_: "+fmt.Sprintf("%#v", this.Size_)+",\n")
+	s = append(s, "}")
+	return strings.Join(s, "")
+}
+func valueToGoStringEntanglement(v interface{}, typ string) string {
+	rv := reflect.ValueOf(v)
+	if rv.IsNil() {
+		return "nil"
+	}
+	pv := reflect.Indirect(rv).Interface()
+	return fmt.Sprintf("func(v %v) *%v { return &v } ( %#v )", typ, typ, pv)
+}
+func (m *PBNode) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *PBNode) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *PBNode) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.Prefix) > 0 {
+		i -= len(m.Prefix)
+		copy(dAtA[i:], m.Prefix)
+		i = encodeVarintEntanglement(dAtA, i, uint64(len(m.Prefix)))
+		i--
+		dAtA[i] = 0x32
+	}
+	if m.P != 0 {
+		i = encodeVarintEntanglement(dAtA, i, uint64(m.P))
+		i--
+		dAtA[i] = 0x28
+	}
+	if m.S != 0 {
+		i = encodeVarintEntanglement(dAtA, i, uint64(m.S))
+		i--
+		dAtA[i] = 0x20
+	}
+	if m.Alpha != 0 {
+		i = encodeVarintEntanglement(dAtA, i, uint64(m.Alpha))
+		i--
+		dAtA[i] = 0x18
+	}
+	if len(m.Parity) > 0 {
+		for iNdEx := len(m.Parity) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Parity[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintEntanglement(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x12
+		}
+	}
+	if len(m.Proto) > 0 {
+		i -= len(m.Proto)
+		copy(dAtA[i:], m.Proto)
+		i = encodeVarintEntanglement(dAtA, i, uint64(len(m.Proto)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *PBLink) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *PBLink) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *PBLink) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.Size_ != 0 {
+		i = encodeVarintEntanglement(dAtA, i, uint64(m.Size_))
+		i--
+		dAtA[i] = 0x18
+	}
+	if len(m.Name) > 0 {
+		i -= len(m.Name)
+		copy(dAtA[i:], m.Name)
+		i = encodeVarintEntanglement(dAtA, i, uint64(len(m.Name)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if len(m.Hash) > 0 {
+		i -= len(m.Hash)
+		copy(dAtA[i:], m.Hash)
+		i = encodeVarintEntanglement(dAtA, i, uint64(len(m.Hash)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func encodeVarintEntanglement(dAtA []byte, offset int, v uint64) int {
+	offset -= sovEntanglement(v)
+	base := offset
+	for v >= 1<<7 {
+		dAtA[offset] = uint8(v&0x7f | 0x80)
+		v >>= 7
+		offset++
+	}
+	dAtA[offset] = uint8(v)
+	return base
+}
+func (m *PBNode) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Proto)
+	if l > 0 {
+		n += 1 + l + sovEntanglement(uint64(l))
+	}
+	if len(m.Parity) > 0 {
+		for _, e := range m.Parity {
+			l = e.Size()
+			n += 1 + l + sovEntanglement(uint64(l))
+		}
+	}
+	if m.Alpha != 0 {
+		n += 1 + sovEntanglement(uint64(m.Alpha))
+	}
+	if m.S != 0 {
+		n += 1 + sovEntanglement(uint64(m.S))
+	}
+	if m.P != 0 {
+		n += 1 + sovEntanglement(uint64(m.P))
+	}
+	l = len(m.Prefix)
+	if l > 0 {
+		n += 1 + l + sovEntanglement(uint64(l))
+	}
+	return n
+}
+
+func (m *PBLink) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Hash)
+	if l > 0 {
+		n += 1 + l + sovEntanglement(uint64(l))
+	}
+	l = len(m.Name)
+	if l > 0 {
+		n += 1 + l + sovEntanglement(uint64(l))
+	}
+	if m.Size_ != 0 {
+		n += 1 + sovEntanglement(uint64(m.Size_))
+	}
+	return n
+}
+
+func sovEntanglement(x uint64) (n int) {
+	return (math_bits.Len64(x|1) + 6) / 7
+}
+func sozEntanglement(x uint64) (n int) {
+	return sovEntanglement(uint64((x << 1) ^ uint64((int64(x) >> 63))))
+}
+func (this *PBNode) String() string {
+	if this == nil {
+		return "nil"
+	}
+	repeatedStringForParity := "[]*PBLink{"
+	for _, f := range this.Parity {
+		repeatedStringForParity += strings.Replace(f.String(), "PBLink", "PBLink", 1) + ","
+	}
+	repeatedStringForParity += "}"
+	s := strings.Join([]string{`&PBNode{`,
+		`Proto:` + fmt.Sprintf("%v", this.Proto) + `,`,
+		`Parity:` + repeatedStringForParity + `,`,
+		`Alpha:` + fmt.Sprintf("%v", this.Alpha) + `,`,
+		`S:` + fmt.Sprintf("%v", this.S) + `,`,
+		`P:` + fmt.Sprintf("%v", this.P) + `,`,
+		`Prefix:` + fmt.Sprintf("%v", this.Prefix) + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func (this *PBLink) String() string {
+	if this == nil {
+		return "nil"
+	}
+	s := strings.Join([]string{`&PBLink{`,
+		`Hash:` + fmt.Sprintf("%v", this.Hash) + `,`,
+		`Name:` + fmt.Sprintf("%v", this.Name) + `,`,
+		`Size_:` + fmt.Sprintf("%v", this.Size_) + `,`,
+		`}`,
+	}, "")
+	return s
+}
+func valueToStringEntanglement(v interface{}) string {
+	rv := reflect.ValueOf(v)
+	if rv.IsNil() {
+		return "nil"
+	}
+	pv := reflect.Indirect(rv).Interface()
+	return fmt.Sprintf("*%v", pv)
+}
+func (m *PBNode) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowEntanglement
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: PBNode: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: PBNode: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Proto", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEntanglement
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthEntanglement
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthEntanglement
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Proto = append(m.Proto[:0], dAtA[iNdEx:postIndex]...)
+			if m.Proto == nil {
+				m.Proto = []byte{}
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Parity", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEntanglement
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthEntanglement
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthEntanglement
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Parity = append(m.Parity, &PBLink{})
+			if err := m.Parity[len(m.Parity)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Alpha", wireType)
+			}
+			m.Alpha = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEntanglement
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Alpha |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field S", wireType)
+			}
+			m.S = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEntanglement
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.S |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field P", wireType)
+			}
+			m.P = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEntanglement
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.P |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 6:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Prefix", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEntanglement
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthEntanglement
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthEntanglement
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Prefix = append(m.Prefix[:0], dAtA[iNdEx:postIndex]...)
+			if m.Prefix == nil {
+				m.Prefix = []byte{}
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipEntanglement(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthEntanglement
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthEntanglement
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *PBLink) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowEntanglement
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: PBLink: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: PBLink: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Hash", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEntanglement
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthEntanglement
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthEntanglement
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Hash = append(m.Hash[:0], dAtA[iNdEx:postIndex]...)
+			if m.Hash == nil {
+				m.Hash = []byte{}
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Name", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEntanglement
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthEntanglement
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthEntanglement
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Name = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Size_", wireType)
+			}
+			m.Size_ = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEntanglement
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Size_ |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipEntanglement(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if skippy < 0 {
+				return ErrInvalidLengthEntanglement
+			}
+			if (iNdEx + skippy) < 0 {
+				return ErrInvalidLengthEntanglement
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func skipEntanglement(dAtA []byte) (n int, err error) {
+	l := len(dAtA)
+	iNdEx := 0
+	depth := 0
+	for iNdEx < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return 0, ErrIntOverflowEntanglement
+			}
+			if iNdEx >= l {
+				return 0, io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		wireType := int(wire & 0x7)
+		switch wireType {
+		case 0:
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return 0, ErrIntOverflowEntanglement
+				}
+				if iNdEx >= l {
+					return 0, io.ErrUnexpectedEOF
+				}
+				iNdEx++
+				if dAtA[iNdEx-1] < 0x80 {
+					break
+				}
+			}
+		case 1:
+			iNdEx += 8
+		case 2:
+			var length int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return 0, ErrIntOverflowEntanglement
+				}
+				if iNdEx >= l {
+					return 0, io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				length |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if length < 0 {
+				return 0, ErrInvalidLengthEntanglement
+			}
+			iNdEx += length
+		case 3:
+			depth++
+		case 4:
+			if depth == 0 {
+				return 0, ErrUnexpectedEndOfGroupEntanglement
+			}
+			depth--
+		case 5:
+			iNdEx += 4
+		default:
+			return 0, fmt.Errorf("proto: illegal wireType %d", wireType)
+		}
+		if iNdEx < 0 {
+			return 0, ErrInvalidLengthEntanglement
+		}
+		if depth == 0 {
+			return iNdEx, nil
+		}
+	}
+	return 0, io.ErrUnexpectedEOF
+}
+
+var (
+	ErrInvalidLengthEntanglement        = fmt.Errorf("proto: negative length found during unmarshaling")
+	ErrIntOverflowEntanglement          = fmt.Errorf("proto: integer overflow")
+	ErrUnexpectedEndOfGroupEntanglement = fmt.Errorf("proto: unexpected end of group")
+)
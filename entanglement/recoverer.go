@@ -0,0 +1,124 @@
+package entanglement
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	format "github.com/ipfs/go-ipld-format"
+
+	"github.com/Wondertan/go-ipfs-recovery"
+	"github.com/Wondertan/go-ipfs-recovery/internal/session"
+)
+
+type recoverer struct {
+	*session.Recoverer
+	strg recovery.Strategy
+}
+
+// RecovererOption configures the Recoverer created with NewRecoverer.
+type RecovererOption func(*session.Options)
+
+// IdleTimeout stops recovery sessions having no pending requests for the given duration.
+// Sessions are stopped only once all data is fetched or repaired otherwise.
+func IdleTimeout(d time.Duration) RecovererOption {
+	return func(o *session.Options) {
+		o.Idle = d
+	}
+}
+
+// MaxSessions bounds the amount of concurrent recovery sessions, each fetching the lattice of one entangled Node.
+// Recover waits for a session to finish, if the limit is reached. Sessions are unbounded by default.
+// Bounded sessions are stopped once idle after recovery.DefaultIdleTimeout, unless IdleTimeout is given.
+func MaxSessions(n int) RecovererOption {
+	return func(o *session.Options) {
+		o.MaxSessions = n
+	}
+}
+
+// NewRecoverer creates new Alpha Entanglement Recoverer.
+func NewRecoverer(ctx context.Context, dag format.DAGService, strg recovery.Strategy, opts ...RecovererOption) recovery.Recoverer {
+	o := session.Options{Strategy: strg}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return &recoverer{
+		Recoverer: session.NewRecoverer(ctx, dag, "entanglement", o),
+		strg:      strg,
+	}
+}
+
+func (r *recoverer) Recover(ctx context.Context, nd recovery.Node, ids ...cid.Cid) (<-chan *format.NodeOption, error) {
+	end, ok := nd.(*Node)
+	if !ok {
+		return nil, fmt.Errorf("entanglement: wrong Node type")
+	}
+
+	for _, id := range ids {
+		if !linked(end, id) {
+			return nil, fmt.Errorf("entanglement: wrong child")
+		}
+	}
+
+	// all the requests for the same Node share one session, so the lattice is fetched only once.
+	return r.Recoverer.Recover(ctx, end.Cid(), func() (session.Shards, error) {
+		br, err := newBraid(end)
+		if err != nil {
+			return nil, err
+		}
+
+		return &shards{braid: br, strg: r.strg}, nil
+	}, ids...)
+}
+
+// linked checks whether the id is linked from the Node either as data or as parity.
+func linked(end *Node, id cid.Cid) bool {
+	for _, l := range end.Links() {
+		if l.Cid.Equals(id) {
+			return true
+		}
+	}
+	for _, l := range end.RecoveryLinks() {
+		if l.Cid.Equals(id) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// shards adapts the braid to the recovery session.
+type shards struct {
+	*braid
+	strg recovery.Strategy
+}
+
+// Want does nothing, as the whole lattice is always fetched.
+func (sh *shards) Want(cid.Cid) error {
+	return nil
+}
+
+// Fill puts the Node into the braid and repairs it progressively, reporting whether some data is still missing.
+func (sh *shards) Fill(nd format.Node) bool {
+	sh.braid.Fill(nd)
+	return !sh.Repair()
+}
+
+// Available checks whether the block is available either from filling or repairing.
+func (sh *shards) Available(id cid.Cid) bool {
+	return sh.Has(id)
+}
+
+// Stored returns Nodes repaired according to the Strategy, or only the responded ones.
+func (sh *shards) Stored(responded []format.Node) ([]format.Node, error) {
+	switch {
+	case sh.strg.All():
+		return sh.All()
+	case sh.strg.Data():
+		return sh.Data()
+	default:
+		return responded, nil
+	}
+}
@@ -0,0 +1,302 @@
+package entanglement
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-blockservice"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/sync"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	offline "github.com/ipfs/go-ipfs-exchange-offline"
+	format "github.com/ipfs/go-ipld-format"
+	"github.com/ipfs/go-merkledag"
+	dstest "github.com/ipfs/go-merkledag/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	recovery "github.com/Wondertan/go-ipfs-recovery"
+	"github.com/Wondertan/go-ipfs-recovery/test"
+)
+
+func TestRecoverer(t *testing.T) {
+	ctx := context.Background()
+	dag := dstest.Mock()
+	rec := NewRecoverer(ctx, dag, recovery.Requested)
+
+	prnt := merkledag.NodeWithData([]byte("1234567890"))
+	ch1 := merkledag.NodeWithData([]byte("03243423423423"))
+	ch2 := merkledag.NodeWithData([]byte("123450"))
+	ch3 := merkledag.NodeWithData([]byte("1234509876"))
+	prnt.AddNodeLink("link", ch1)
+	prnt.AddNodeLink("link", ch2)
+	prnt.AddNodeLink("link", ch3)
+	dag.AddMany(ctx, []format.Node{prnt, ch1, ch2, ch3})
+
+	enc, err := Encode(ctx, dag, prnt, 2, 2, 2)
+	require.NoError(t, err)
+
+	dag.Remove(ctx, ch1.Cid())
+	dag.Remove(ctx, ch3.Cid())
+	dag.Remove(ctx, enc.RecoveryLinks()[0].Cid)
+
+	out, err := rec.Recover(ctx, enc, ch1.Cid(), ch3.Cid())
+	require.NoError(t, err)
+
+	no := <-out
+	require.NoError(t, no.Err)
+	assert.Equal(t, ch1.RawData(), no.Node.RawData())
+
+	no = <-out
+	require.NoError(t, no.Err)
+	assert.Equal(t, ch3.RawData(), no.Node.RawData())
+
+	_, err = dag.Get(ctx, ch1.Cid())
+	assert.NoError(t, err)
+}
+
+func TestRecovererUnixFS(t *testing.T) {
+	ctx := context.Background()
+
+	bstore := blockstore.NewBlockstore(sync.MutexWrap(datastore.NewMapDatastore()))
+	ex := offline.Exchange(bstore)
+	dag := merkledag.NewDAGService(blockservice.New(bstore, ex))
+
+	dr := test.NewFSDagger(t, ctx, &merkledag.ComboService{
+		Read:  recovery.NewDagSession(ctx, NewRecoverer(ctx, dag, recovery.All), ex, bstore),
+		Write: dag,
+	})
+	dr.Morpher = func(nd format.Node) (format.Node, error) {
+		if len(nd.Links()) == 0 {
+			return nd, nil
+		}
+
+		return Encode(ctx, dag, nd, 3, 2, 3)
+	}
+
+	root :=
+		dr.NewDir("root",
+			dr.RandNode("file1"),
+			dr.RandNode("file2"),
+			dr.NewDir("dir1",
+				dr.RandNode("file3"),
+				dr.RandNode("file4"),
+			),
+			dr.NewDir("dir2",
+				dr.RandNode("file5"),
+			),
+		)
+
+	dr.Remove("file1")
+	dr.Remove("dir2")
+	dr.Remove("file4")
+
+	root.Validate()
+}
+
+// stallingDAG never closes GetMany channel until the context is done, like the network does.
+type stallingDAG struct {
+	format.DAGService
+}
+
+func (d *stallingDAG) GetMany(ctx context.Context, ids []cid.Cid) <-chan *format.NodeOption {
+	out := make(chan *format.NodeOption)
+	go func() {
+		defer close(out)
+		for no := range d.DAGService.GetMany(ctx, ids) {
+			select {
+			case out <- no:
+			case <-ctx.Done():
+				return
+			}
+		}
+		<-ctx.Done()
+	}()
+	return out
+}
+
+func TestRecovererClose(t *testing.T) {
+	ctx := context.Background()
+	dag := dstest.Mock()
+	rec := NewRecoverer(ctx, &stallingDAG{dag}, recovery.Requested)
+
+	prnt := merkledag.NodeWithData([]byte("1234567890"))
+	ch1 := merkledag.NodeWithData([]byte("03243423423423"))
+	ch2 := merkledag.NodeWithData([]byte("123450"))
+	prnt.AddNodeLink("link", ch1)
+	prnt.AddNodeLink("link", ch2)
+	dag.AddMany(ctx, []format.Node{prnt, ch1, ch2})
+
+	enc, err := Encode(ctx, dag, prnt, 1, 1, 1)
+	require.NoError(t, err)
+
+	// nothing is left to repair from
+	dag.Remove(ctx, ch1.Cid())
+	for _, l := range enc.RecoveryLinks() {
+		dag.Remove(ctx, l.Cid)
+	}
+
+	out, err := rec.Recover(ctx, enc, ch1.Cid())
+	require.NoError(t, err)
+	require.NoError(t, rec.Close())
+
+	select {
+	case no := <-out:
+		assert.Error(t, no.Err)
+	case <-time.After(time.Second):
+		t.Fatal("pending request is not responded on Close")
+	}
+
+	_, ok := <-out
+	assert.False(t, ok)
+
+	_, err = rec.Recover(ctx, enc, ch1.Cid())
+	assert.Error(t, err)
+}
+
+func TestRecovererCancel(t *testing.T) {
+	ctx := context.Background()
+	dag := dstest.Mock()
+	rec := NewRecoverer(ctx, &stallingDAG{dag}, recovery.Requested)
+	defer rec.Close()
+
+	prnt := merkledag.NodeWithData([]byte("1234567890"))
+	ch1 := merkledag.NodeWithData([]byte("03243423423423"))
+	ch2 := merkledag.NodeWithData([]byte("123450"))
+	prnt.AddNodeLink("link", ch1)
+	prnt.AddNodeLink("link", ch2)
+	dag.AddMany(ctx, []format.Node{prnt, ch1, ch2})
+
+	enc, err := Encode(ctx, dag, prnt, 1, 1, 1)
+	require.NoError(t, err)
+
+	dag.Remove(ctx, ch1.Cid())
+	for _, l := range enc.RecoveryLinks() {
+		dag.Remove(ctx, l.Cid)
+	}
+
+	rctx, cancel := context.WithCancel(ctx)
+	out, err := rec.Recover(rctx, enc, ch1.Cid())
+	require.NoError(t, err)
+
+	cancel()
+	no := <-out
+	assert.Equal(t, context.Canceled, no.Err)
+	_, ok := <-out
+	assert.False(t, ok)
+}
+
+func TestRecovererMaxSessions(t *testing.T) {
+	ctx := context.Background()
+	dag := dstest.Mock()
+	rec := NewRecoverer(ctx, &stallingDAG{dag}, recovery.All, MaxSessions(1), IdleTimeout(50*time.Millisecond))
+	defer rec.Close()
+
+	unrepairable := func(data string) (*Node, format.Node) {
+		prnt := merkledag.NodeWithData([]byte(data))
+		ch1 := merkledag.NodeWithData([]byte(data + "1"))
+		ch2 := merkledag.NodeWithData([]byte(data + "2"))
+		prnt.AddNodeLink("link", ch1)
+		prnt.AddNodeLink("link", ch2)
+		dag.AddMany(ctx, []format.Node{prnt, ch1, ch2})
+
+		enc, err := Encode(ctx, dag, prnt, 1, 1, 1)
+		require.NoError(t, err)
+
+		dag.Remove(ctx, ch1.Cid())
+		for _, l := range enc.RecoveryLinks() {
+			dag.Remove(ctx, l.Cid)
+		}
+		return enc, ch2
+	}
+	enc1, has1 := unrepairable("1234567890")
+	enc2, has2 := unrepairable("0987654321")
+
+	// the request is fully served, but the session still waits for the rest of the lattice.
+	out1, err := rec.Recover(ctx, enc1, has1.Cid())
+	require.NoError(t, err)
+	no := <-out1
+	require.NoError(t, no.Err)
+	assert.Equal(t, has1.RawData(), no.Node.RawData())
+
+	// so the slot is freed only once the session is idle.
+	tctx, tcancel := context.WithTimeout(ctx, time.Second)
+	defer tcancel()
+	out2, err := rec.Recover(tctx, enc2, has2.Cid())
+	require.NoError(t, err)
+	no = <-out2
+	require.NoError(t, no.Err)
+	assert.Equal(t, has2.RawData(), no.Node.RawData())
+}
+
+// gatedDAG counts GetMany calls and holds their results until the gate is open.
+type gatedDAG struct {
+	format.DAGService
+	gate chan struct{}
+	n    int32
+}
+
+func (d *gatedDAG) GetMany(ctx context.Context, ids []cid.Cid) <-chan *format.NodeOption {
+	atomic.AddInt32(&d.n, 1)
+
+	out := make(chan *format.NodeOption)
+	go func() {
+		defer close(out)
+		select {
+		case <-d.gate:
+		case <-ctx.Done():
+			return
+		}
+
+		for no := range d.DAGService.GetMany(ctx, ids) {
+			select {
+			case out <- no:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+func TestRecovererShared(t *testing.T) {
+	ctx := context.Background()
+	dag := dstest.Mock()
+	gdag := &gatedDAG{DAGService: dag, gate: make(chan struct{})}
+	rec := NewRecoverer(ctx, gdag, recovery.Requested)
+	defer rec.Close()
+
+	prnt := merkledag.NodeWithData([]byte("1234567890"))
+	ch1 := merkledag.NodeWithData([]byte("03243423423423"))
+	ch2 := merkledag.NodeWithData([]byte("123450"))
+	ch3 := merkledag.NodeWithData([]byte("1234509876"))
+	prnt.AddNodeLink("link", ch1)
+	prnt.AddNodeLink("link", ch2)
+	prnt.AddNodeLink("link", ch3)
+	dag.AddMany(ctx, []format.Node{prnt, ch1, ch2, ch3})
+
+	enc, err := Encode(ctx, dag, prnt, 2, 2, 2)
+	require.NoError(t, err)
+
+	lost := []format.Node{ch1, ch3}
+	dag.Remove(ctx, ch1.Cid())
+	dag.Remove(ctx, ch3.Cid())
+
+	// concurrent recoveries of different children from the same Node fetch the lattice once.
+	outs := make([]<-chan *format.NodeOption, len(lost))
+	for i, ch := range lost {
+		outs[i], err = rec.Recover(ctx, enc, ch.Cid())
+		require.NoError(t, err)
+	}
+	close(gdag.gate)
+
+	for i, out := range outs {
+		no := <-out
+		require.NoError(t, no.Err)
+		assert.Equal(t, lost[i].RawData(), no.Node.RawData())
+	}
+	assert.EqualValues(t, 1, atomic.LoadInt32(&gdag.n))
+}
@@ -0,0 +1,137 @@
+package recovery
+
+import (
+	"context"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/namespace"
+	"github.com/ipfs/go-datastore/query"
+	format "github.com/ipfs/go-ipld-format"
+)
+
+var parentsPrefix = datastore.NewKey("/recovery/parents")
+
+// ParentIndex is a persistent reverse index from linked Nodes to recovery Nodes linking them.
+// It allows to recover Nodes requested directly by CID, without traversing the DAG from the root.
+type ParentIndex struct {
+	ds datastore.Datastore
+}
+
+// NewParentIndex creates new ParentIndex on top of the given Datastore.
+func NewParentIndex(ds datastore.Datastore) *ParentIndex {
+	return &ParentIndex{ds: namespace.Wrap(ds, parentsPrefix)}
+}
+
+// Put indexes all the Nodes linked by the recovery Node.
+func (pi *ParentIndex) Put(nd Node) error {
+	for _, l := range nd.Links() {
+		err := pi.ds.Put(parentKey(l.Cid, nd.Cid()), nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, l := range nd.RecoveryLinks() {
+		err := pi.ds.Put(parentKey(l.Cid, nd.Cid()), nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Remove forgets the recovery Node for all the Nodes it links.
+func (pi *ParentIndex) Remove(nd Node) error {
+	for _, l := range nd.Links() {
+		err := pi.ds.Delete(parentKey(l.Cid, nd.Cid()))
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, l := range nd.RecoveryLinks() {
+		err := pi.ds.Delete(parentKey(l.Cid, nd.Cid()))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Get lists all known recovery Nodes linking the Node with the given id.
+func (pi *ParentIndex) Get(id cid.Cid) ([]cid.Cid, error) {
+	res, err := pi.ds.Query(query.Query{Prefix: datastore.NewKey(id.String()).String(), KeysOnly: true})
+	if err != nil {
+		return nil, err
+	}
+
+	es, err := res.Rest()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]cid.Cid, 0, len(es))
+	for _, e := range es {
+		id, err := cid.Decode(datastore.RawKey(e.Key).BaseNamespace())
+		if err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+func parentKey(child, prnt cid.Cid) datastore.Key {
+	return datastore.KeyWithNamespaces([]string{child.String(), prnt.String()})
+}
+
+// indexedDAG indexes every recovery Node added to the DAG.
+type indexedDAG struct {
+	format.DAGService
+	pi *ParentIndex
+}
+
+// NewIndexedDAG wraps the DAGService to populate the ParentIndex with all the recovery Nodes added,
+// e.g. when it is used for encoding.
+func NewIndexedDAG(dag format.DAGService, pi *ParentIndex) format.DAGService {
+	return &indexedDAG{DAGService: dag, pi: pi}
+}
+
+func (dag *indexedDAG) Add(ctx context.Context, nd format.Node) error {
+	err := dag.DAGService.Add(ctx, nd)
+	if err != nil {
+		return err
+	}
+
+	return dag.index(nd)
+}
+
+func (dag *indexedDAG) AddMany(ctx context.Context, nds []format.Node) error {
+	err := dag.DAGService.AddMany(ctx, nds)
+	if err != nil {
+		return err
+	}
+
+	for _, nd := range nds {
+		err = dag.index(nd)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (dag *indexedDAG) index(nd format.Node) error {
+	rn, ok := nd.(Node)
+	if !ok {
+		return nil
+	}
+
+	return dag.pi.Put(rn)
+}
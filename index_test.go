@@ -0,0 +1,78 @@
+package recovery_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-blockservice"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/sync"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	offline "github.com/ipfs/go-ipfs-exchange-offline"
+	format "github.com/ipfs/go-ipld-format"
+	"github.com/ipfs/go-merkledag"
+	dstest "github.com/ipfs/go-merkledag/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	recovery "github.com/Wondertan/go-ipfs-recovery"
+	"github.com/Wondertan/go-ipfs-recovery/reedsolomon"
+)
+
+func TestParentIndex(t *testing.T) {
+	ctx := context.Background()
+	pi := recovery.NewParentIndex(datastore.NewMapDatastore())
+	dag := recovery.NewIndexedDAG(dstest.Mock(), pi)
+
+	prnt := merkledag.NodeWithData([]byte("1234567890"))
+	ch1 := merkledag.NodeWithData([]byte("03243423423423"))
+	ch2 := merkledag.NodeWithData([]byte("123450"))
+	prnt.AddNodeLink("link", ch1)
+	prnt.AddNodeLink("link", ch2)
+	require.NoError(t, dag.AddMany(ctx, []format.Node{prnt, ch1, ch2}))
+
+	enc, err := reedsolomon.Encode(ctx, dag, prnt, 1)
+	require.NoError(t, err)
+
+	for _, id := range []format.Node{ch1, ch2} {
+		ids, err := pi.Get(id.Cid())
+		require.NoError(t, err)
+		require.Len(t, ids, 1)
+		assert.True(t, enc.Cid().Equals(ids[0]))
+	}
+
+	ids, err := pi.Get(enc.RecoveryLinks()[0].Cid)
+	require.NoError(t, err)
+	assert.Len(t, ids, 1)
+
+	ids, err = pi.Get(prnt.Cid())
+	require.NoError(t, err)
+	assert.Len(t, ids, 0)
+}
+
+func TestDagSessionParentIndex(t *testing.T) {
+	ctx := context.Background()
+
+	ds := sync.MutexWrap(datastore.NewMapDatastore())
+	bstore := blockstore.NewBlockstore(ds)
+	ex := offline.Exchange(bstore)
+	pi := recovery.NewParentIndex(ds)
+	dag := merkledag.NewDAGService(blockservice.New(bstore, ex))
+
+	prnt := merkledag.NodeWithData([]byte("1234567890"))
+	ch1 := merkledag.NodeWithData([]byte("03243423423423"))
+	ch2 := merkledag.NodeWithData([]byte("123450"))
+	prnt.AddNodeLink("link", ch1)
+	prnt.AddNodeLink("link", ch2)
+	require.NoError(t, dag.AddMany(ctx, []format.Node{prnt, ch1, ch2}))
+
+	_, err := reedsolomon.Encode(ctx, recovery.NewIndexedDAG(dag, pi), prnt, 1)
+	require.NoError(t, err)
+	dag.Remove(ctx, ch1.Cid())
+
+	// the session has never seen the parent
+	ses := recovery.NewDagSession(ctx, reedsolomon.NewRecoverer(ctx, dag, recovery.Requested), ex, bstore, recovery.WithParentIndex(pi))
+	nd, err := ses.Get(ctx, ch1.Cid())
+	require.NoError(t, err)
+	assert.Equal(t, ch1.RawData(), nd.RawData())
+}
@@ -0,0 +1,431 @@
+// Package session implements recovery sessions shared by Recoverers of all the coding algorithms.
+// All the requests for the same recovery Node share one session, so its Nodes are fetched only once.
+package session
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ipfs/go-cid"
+	format "github.com/ipfs/go-ipld-format"
+	logging "github.com/ipfs/go-log/v2"
+
+	"github.com/Wondertan/go-ipfs-recovery"
+)
+
+var log = logging.Logger("recovery")
+
+// Shards is the coding state of the recovery Node, which the session fills with fetched Nodes and recovers Nodes from.
+type Shards interface {
+	// Parent returns CID of the recovery Node.
+	Parent() cid.Cid
+	// IDs returns CIDs of all the Nodes to fetch.
+	IDs() []cid.Cid
+	// Want marks the Node as requested.
+	Want(id cid.Cid) error
+	// Fill puts the fetched Node, reporting whether more Nodes are needed.
+	Fill(nd format.Node) bool
+	// Available checks whether the Node is available either from filling or recovery.
+	Available(id cid.Cid) bool
+	// Get returns the available Node or the error if it can't be recovered.
+	Get(id cid.Cid) (format.Node, error)
+	// Stored returns Nodes to store once the session is finished, given the responded ones.
+	Stored(responded []format.Node) ([]format.Node, error)
+}
+
+// Options configure the Recoverer.
+type Options struct {
+	// Strategy defines Nodes to recover, sessions recovering only requested ones stop once all of them are responded.
+	Strategy recovery.Strategy
+	// Idle is the duration sessions without pending requests are stopped after, zero disables it.
+	Idle time.Duration
+	// MaxSessions bounds the amount of concurrent sessions, non-positive is unbounded.
+	// Bounded sessions are always stopped once idle, after recovery.DefaultIdleTimeout unless Idle is given.
+	MaxSessions int
+}
+
+// Recoverer manages recovery sessions, one per recovery Node.
+type Recoverer struct {
+	ctx       context.Context
+	cancel    context.CancelFunc
+	dag       format.DAGService
+	errClosed error
+
+	recs   map[cid.Cid]*recoverySes
+	rl     sync.RWMutex
+	wg     sync.WaitGroup
+	closed bool
+
+	strg recovery.Strategy
+	idle time.Duration
+	sem  chan struct{}
+}
+
+// NewRecoverer creates new Recoverer fetching Nodes from and storing recovered ones to the DAG.
+// The name prefixes errors of the Recoverer.
+func NewRecoverer(ctx context.Context, dag format.DAGService, name string, opts Options) *Recoverer {
+	r := &Recoverer{
+		dag:       dag,
+		errClosed: fmt.Errorf("%s: Recoverer is closed", name),
+		recs:      make(map[cid.Cid]*recoverySes),
+		strg:      opts.Strategy,
+		idle:      opts.Idle,
+	}
+	if opts.MaxSessions > 0 {
+		r.sem = make(chan struct{}, opts.MaxSessions)
+		if r.idle <= 0 {
+			r.idle = recovery.DefaultIdleTimeout
+		}
+	}
+	r.ctx, r.cancel = context.WithCancel(ctx)
+	return r
+}
+
+// Recover requests Nodes by ids from the session for the parent, starting the new one with Shards made by newShards,
+// if there is none.
+func (r *Recoverer) Recover(ctx context.Context, prnt cid.Cid, newShards func() (Shards, error), ids ...cid.Cid) (<-chan *format.NodeOption, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	for {
+		req := newRequest(ctx, ids)
+		rc, started, err := r.session(ctx, prnt, newShards, req)
+		if err != nil {
+			return nil, err
+		}
+		if started || rc.recover(req) {
+			return req.out, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		// session has just finished, so forget it and start a new one.
+		r.forget(rc)
+	}
+}
+
+// Close stops all the recovery sessions, responding to pending requests with what is recovered so far.
+func (r *Recoverer) Close() error {
+	r.rl.Lock()
+	r.closed = true
+	r.rl.Unlock()
+
+	r.cancel()
+	r.wg.Wait()
+	return nil
+}
+
+// Sessions returns the amount of running sessions.
+func (r *Recoverer) Sessions() int {
+	r.rl.RLock()
+	defer r.rl.RUnlock()
+	return len(r.recs)
+}
+
+// session gets the recovery session for the parent or starts a new one with the request, once there is a free slot
+// for it.
+func (r *Recoverer) session(ctx context.Context, prnt cid.Cid, newShards func() (Shards, error), req *rcvrReq) (_ *recoverySes, started bool, err error) {
+	r.rl.RLock()
+	rc, ok := r.recs[prnt]
+	r.rl.RUnlock()
+	if ok {
+		return rc, false, nil
+	}
+
+	err = r.acquire(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	r.rl.Lock()
+	defer r.rl.Unlock()
+
+	rc, ok = r.recs[prnt]
+	if ok {
+		r.release()
+		return rc, false, nil
+	}
+	if r.closed {
+		r.release()
+		return nil, false, r.errClosed
+	}
+
+	sh, err := newShards()
+	if err != nil {
+		r.release()
+		return nil, false, err
+	}
+
+	rc = r.newRecovery(sh, req)
+	r.recs[prnt] = rc
+	return rc, true, nil
+}
+
+// acquire takes a slot for a new session.
+func (r *Recoverer) acquire(ctx context.Context) error {
+	if r.sem == nil {
+		return nil
+	}
+
+	select {
+	case r.sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-r.ctx.Done():
+		return r.errClosed
+	}
+}
+
+// release frees the slot of a finished session.
+func (r *Recoverer) release() {
+	if r.sem != nil {
+		<-r.sem
+	}
+}
+
+// forget removes the session if it is still tracked.
+func (r *Recoverer) forget(rc *recoverySes) {
+	r.rl.Lock()
+	defer r.rl.Unlock()
+
+	if r.recs[rc.sh.Parent()] == rc {
+		delete(r.recs, rc.sh.Parent())
+	}
+}
+
+type recoverySes struct {
+	r *Recoverer
+
+	getCncl context.CancelFunc
+
+	reqCh  chan *rcvrReq
+	cnclCh chan *rcvrReq
+	reqs   []*rcvrReq // pending requests, fully responded ones are forgotten
+	done   chan struct{}
+
+	in  <-chan *format.NodeOption
+	sh  Shards
+	nds []format.Node // responded Nodes
+}
+
+// newRecovery starts a new recovery session serving the request first.
+func (r *Recoverer) newRecovery(sh Shards, req *rcvrReq) *recoverySes {
+	getCtx, getCncl := context.WithCancel(r.ctx)
+	// blockservice filters given ids in place, so pass a copy to not race with the session.
+	in := r.dag.GetMany(getCtx, append([]cid.Cid(nil), sh.IDs()...))
+
+	rc := &recoverySes{
+		r:       r,
+		getCncl: getCncl,
+		reqCh:   make(chan *rcvrReq),
+		cnclCh:  make(chan *rcvrReq),
+		reqs:    make([]*rcvrReq, 0, 1),
+		done:    make(chan struct{}),
+		in:      in,
+		sh:      sh,
+	}
+	rc.accept(req)
+
+	r.wg.Add(1)
+	go rc.handle()
+	return rc
+}
+
+type rcvrReq struct {
+	ctx context.Context
+	ids []cid.Cid
+	out chan *format.NodeOption
+}
+
+func newRequest(ctx context.Context, ids []cid.Cid) *rcvrReq {
+	req := &rcvrReq{ctx: ctx, ids: make([]cid.Cid, len(ids)), out: make(chan *format.NodeOption, len(ids))}
+	copy(req.ids, ids) // ids are removed from the request as they are responded
+	return req
+}
+
+// recover passes the request to the session, reporting whether the session accepted it.
+func (r *recoverySes) recover(req *rcvrReq) bool {
+	select {
+	case r.reqCh <- req:
+		return true
+	case <-r.done:
+		return false
+	case <-req.ctx.Done():
+		return false
+	}
+}
+
+// accept registers the request and watches for its context to be done.
+func (r *recoverySes) accept(req *rcvrReq) {
+	for _, id := range req.ids {
+		err := r.sh.Want(id)
+		if err != nil {
+			log.Error(err)
+		}
+	}
+
+	r.reqs = append(r.reqs, req)
+	go func() {
+		select {
+		case <-req.ctx.Done():
+			select {
+			case r.cnclCh <- req:
+			case <-r.done:
+			}
+		case <-r.done:
+		}
+	}()
+}
+
+// cancel forgets the request with its context done, responding with the context error if it is still pending.
+func (r *recoverySes) cancel(req *rcvrReq) {
+	for i, pr := range r.reqs {
+		if pr != req {
+			continue
+		}
+
+		r.reqs = append(r.reqs[:i], r.reqs[i+1:]...)
+		req.out <- &format.NodeOption{Err: req.ctx.Err()} // never blocks, as at least one id is not responded yet
+		close(req.out)
+		return
+	}
+}
+
+func (r *recoverySes) handle() {
+	defer r.r.wg.Done()
+	defer r.r.release()
+	defer func() {
+		close(r.done) // no more requests are accepted
+		r.getCncl()
+		r.respond()
+		r.r.forget(r)
+		r.store()
+	}()
+
+	var (
+		idle  *time.Timer
+		idleC <-chan time.Time
+	)
+	if r.r.idle > 0 {
+		idle = time.NewTimer(r.r.idle)
+		defer idle.Stop()
+	}
+
+	// Nodes are recovered progressively, so requests are served as soon as their Nodes are available.
+	r.serve()
+	for {
+		if len(r.reqs) == 0 && !r.r.strg.Data() {
+			return // only requested Nodes are recovered and all of them are responded
+		}
+
+		if idle != nil {
+			// the session is idle only if nothing happens while there is no one waiting for it.
+			if !idle.Stop() {
+				select {
+				case <-idle.C:
+				default:
+				}
+			}
+			idle.Reset(r.r.idle)
+
+			idleC = nil
+			if len(r.reqs) == 0 {
+				idleC = idle.C
+			}
+		}
+
+		select {
+		case <-idleC:
+			return
+		case nd, ok := <-r.in:
+			if !ok {
+				return // everything available is fetched, nothing to wait for.
+			}
+			if nd.Err != nil {
+				log.Error(nd.Err)
+				continue
+			}
+
+			if !r.sh.Fill(nd.Node) {
+				return
+			}
+
+			r.serve()
+		case req := <-r.reqCh:
+			r.accept(req)
+			r.serve()
+		case req := <-r.cnclCh:
+			r.cancel(req)
+			if len(r.reqs) == 0 {
+				return // no one is waiting for the recovery anymore
+			}
+		case <-r.r.ctx.Done():
+			return // the Recoverer is closed, so respond with what is recovered so far
+		}
+	}
+}
+
+// serve responds to pending requests with Nodes available so far and forgets fully responded ones.
+func (r *recoverySes) serve() {
+	reqs := r.reqs[:0]
+	for _, req := range r.reqs {
+		ids := req.ids[:0]
+		for _, id := range req.ids {
+			if !r.sh.Available(id) {
+				ids = append(ids, id)
+				continue
+			}
+
+			r.send(req, id)
+		}
+
+		req.ids = ids
+		if len(req.ids) > 0 {
+			reqs = append(reqs, req)
+		} else {
+			close(req.out)
+		}
+	}
+	r.reqs = reqs
+}
+
+// respond responds to all pending requests with recovered Nodes or errors if recovery is not possible.
+func (r *recoverySes) respond() {
+	for _, req := range r.reqs {
+		for _, id := range req.ids {
+			r.send(req, id)
+		}
+
+		req.ids = nil
+		close(req.out)
+	}
+	r.reqs = nil
+}
+
+func (r *recoverySes) send(req *rcvrReq, id cid.Cid) {
+	nd, err := r.sh.Get(id)
+	if err == nil {
+		r.nds = append(r.nds, nd)
+	}
+
+	req.out <- &format.NodeOption{Node: nd, Err: err} // never blocks, as out is buffered for all the ids
+}
+
+// store saves recovered Nodes.
+func (r *recoverySes) store() {
+	nds, err := r.sh.Stored(r.nds)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	err = r.r.dag.AddMany(r.r.ctx, nds)
+	if err != nil {
+		log.Error(err)
+	}
+}
@@ -0,0 +1,27 @@
+package session
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	dstest "github.com/ipfs/go-merkledag/test"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Wondertan/go-ipfs-recovery"
+)
+
+func TestRecovererIdle(t *testing.T) {
+	ctx := context.Background()
+	dag := dstest.Mock()
+
+	r := NewRecoverer(ctx, dag, "test", Options{})
+	assert.Zero(t, r.idle)
+
+	// bounded sessions are always stopped once idle, so they don't hold their slots forever.
+	r = NewRecoverer(ctx, dag, "test", Options{MaxSessions: 1})
+	assert.Equal(t, recovery.DefaultIdleTimeout, r.idle)
+
+	r = NewRecoverer(ctx, dag, "test", Options{MaxSessions: 1, Idle: time.Second})
+	assert.Equal(t, time.Second, r.idle)
+}
@@ -0,0 +1,180 @@
+package recovery
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/namespace"
+	format "github.com/ipfs/go-ipld-format"
+	"github.com/ipfs/go-merkledag"
+)
+
+var manifestsPrefix = datastore.NewKey("/recovery/manifests")
+
+// EncodeSidecar encodes the whole DAG under the given node like EncodeDAG, but leaves the DAG byte-identical.
+// Every encoded Node gets a detached recovery Node linking the same children, and all of them are linked from the
+// returned root of the manifest DAG by CIDs of the Nodes they protect. Register the manifest in Manifests, so sessions
+// recover Nodes of the unmodified DAG. The NodeGetter must be a NodeAdder to store the manifest.
+// Checkpoint, KeepOriginals and Anchor options do not apply.
+func EncodeSidecar(ctx context.Context, dag format.NodeGetter, e Encoder, nd format.Node, r Recoverability, opts ...EncodeOption) (format.Node, error) {
+	na, ok := dag.(format.NodeAdder)
+	if !ok {
+		return nil, fmt.Errorf("recovery: can't store the manifest with the NodeGetter")
+	}
+
+	de, _ := newDagEncoder(dag, e, r, opts...)
+	de.cp, de.cm, de.sc = nil, nil, &sidecar{nds: make(map[cid.Cid]*format.Link)}
+
+	_, err := de.encode(ctx, nd, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return de.sc.manifest(ctx, na)
+}
+
+// sidecar collects detached recovery Nodes.
+type sidecar struct {
+	nds map[cid.Cid]*format.Link
+	l   sync.Mutex
+}
+
+// sidecar encodes the Node into a detached recovery Node, leaving the original in place.
+func (de *dagEncoder) sidecar(ctx context.Context, orig, nd format.Node, r Recoverability) error {
+	if r <= 0 {
+		return nil
+	}
+
+	end, err := de.e.Encode(ctx, nd, r)
+	if err != nil {
+		return err
+	}
+
+	s, err := end.Size()
+	if err != nil {
+		return err
+	}
+
+	if de.pr != nil {
+		var ps uint64
+		for _, l := range end.RecoveryLinks() {
+			ps += l.Size
+		}
+
+		de.report(EncodeEvent{Type: NodeEncoded, Cid: orig.Cid(), Encoded: end.Cid(), Recoverability: r, ParityBytes: ps})
+	}
+
+	de.sc.l.Lock()
+	de.sc.nds[orig.Cid()] = &format.Link{Name: orig.Cid().String(), Cid: end.Cid(), Size: s}
+	de.sc.l.Unlock()
+	return nil
+}
+
+// ManifestWidth is the maximum amount of links of a manifest Node, so the manifest DAG fits block size limits.
+const ManifestWidth = 1024
+
+// manifest stores the manifest DAG with leaves linking all the recovery Nodes named by CIDs of the Nodes they protect,
+// and inner Nodes linking other manifest Nodes unnamed, and returns its root.
+func (sc *sidecar) manifest(ctx context.Context, na format.NodeAdder) (format.Node, error) {
+	ls := make([]*format.Link, 0, len(sc.nds))
+	for _, l := range sc.nds {
+		ls = append(ls, l)
+	}
+	sort.Sort(merkledag.LinkSlice(ls))
+
+	for {
+		var nds []format.Node
+		for len(ls) > 0 || len(nds) == 0 {
+			n := ManifestWidth
+			if n > len(ls) {
+				n = len(ls)
+			}
+
+			mn := merkledag.NodeWithData(nil)
+			mn.SetLinks(ls[:n])
+			nds, ls = append(nds, mn), ls[n:]
+		}
+
+		err := na.AddMany(ctx, nds)
+		if err != nil {
+			return nil, err
+		}
+		if len(nds) == 1 {
+			return nds[0], nil
+		}
+
+		ls = make([]*format.Link, len(nds))
+		for i, nd := range nds {
+			ls[i], err = format.MakeLink(nd)
+			if err != nil {
+				return nil, err
+			}
+			ls[i].Name = ""
+		}
+	}
+}
+
+// Manifests is a persistent registry of sidecar manifests produced by EncodeSidecar.
+// Sessions consult it to find detached recovery Nodes for Nodes of unmodified DAGs.
+type Manifests struct {
+	ds datastore.Datastore
+}
+
+// NewManifests creates new Manifests on top of the given Datastore.
+func NewManifests(ds datastore.Datastore) *Manifests {
+	return &Manifests{ds: namespace.Wrap(ds, manifestsPrefix)}
+}
+
+// Register remembers all the recovery Nodes linked by the manifest DAG under the given root.
+func (m *Manifests) Register(ctx context.Context, dag format.NodeGetter, mn format.Node) error {
+	for _, l := range mn.Links() {
+		if l.Name == "" {
+			nd, err := l.GetNode(ctx, dag)
+			if err != nil {
+				return err
+			}
+
+			err = m.Register(ctx, dag, nd)
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		id, err := cid.Decode(l.Name)
+		if err != nil {
+			return fmt.Errorf("recovery: wrong manifest link %s: %s", l.Name, err)
+		}
+
+		err = m.ds.Put(datastore.NewKey(id.String()), l.Cid.Bytes())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Get returns the detached recovery Node id for the Node with the given id, if known.
+func (m *Manifests) Get(id cid.Cid) (cid.Cid, bool, error) {
+	v, err := m.ds.Get(datastore.NewKey(id.String()))
+	switch err {
+	case nil:
+	case datastore.ErrNotFound:
+		return cid.Undef, false, nil
+	default:
+		return cid.Undef, false, err
+	}
+
+	rid, err := cid.Cast(v)
+	if err != nil {
+		return cid.Undef, false, err
+	}
+
+	return rid, true, nil
+}
@@ -0,0 +1,103 @@
+package recovery_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-blockservice"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/sync"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	offline "github.com/ipfs/go-ipfs-exchange-offline"
+	format "github.com/ipfs/go-ipld-format"
+	"github.com/ipfs/go-merkledag"
+	dstest "github.com/ipfs/go-merkledag/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	recovery "github.com/Wondertan/go-ipfs-recovery"
+	"github.com/Wondertan/go-ipfs-recovery/reedsolomon"
+)
+
+func TestEncodeSidecar(t *testing.T) {
+	ctx := context.Background()
+
+	ds := sync.MutexWrap(datastore.NewMapDatastore())
+	bstore := blockstore.NewBlockstore(ds)
+	ex := offline.Exchange(bstore)
+	dag := merkledag.NewDAGService(blockservice.New(bstore, ex))
+
+	nd := newTestDAG(t, dag, 3)
+	mn, err := recovery.EncodeSidecar(ctx, dag, reedsolomon.NewEncoder(dag), nd, 1, recovery.Concurrency(2))
+	require.NoError(t, err)
+	assert.Len(t, mn.Links(), 4) // the root and its children
+
+	// the DAG is untouched
+	assert.Equal(t, nd.RawData(), mustGet(t, dag, nd.Cid()).RawData())
+	for _, l := range nd.Links() {
+		_, ok := mustGet(t, dag, l.Cid).(recovery.Node)
+		assert.False(t, ok)
+	}
+
+	mf := recovery.NewManifests(ds)
+	require.NoError(t, mf.Register(ctx, dag, mn))
+
+	ch := mustGet(t, dag, nd.Links()[1].Cid)
+	require.NoError(t, dag.Remove(ctx, ch.Cid()))
+	require.NoError(t, dag.Remove(ctx, ch.Links()[0].Cid))
+
+	ses := recovery.NewDagSession(ctx, reedsolomon.NewRecoverer(ctx, dag, recovery.Requested), ex, bstore,
+		recovery.WithManifests(mf))
+
+	_, err = ses.Get(ctx, nd.Cid())
+	require.NoError(t, err)
+
+	got, err := ses.Get(ctx, ch.Cid())
+	require.NoError(t, err)
+	assert.Equal(t, ch.RawData(), got.RawData())
+
+	gch, err := ses.Get(ctx, ch.Links()[0].Cid)
+	require.NoError(t, err)
+	assert.True(t, ch.Links()[0].Cid.Equals(gch.Cid()))
+}
+
+func TestEncodeSidecarWide(t *testing.T) {
+	ctx := context.Background()
+
+	ds := sync.MutexWrap(datastore.NewMapDatastore())
+	dag := dstest.Mock()
+
+	// every child is encoded, so the manifest doesn't fit a single Node
+	nd := merkledag.NodeWithData([]byte("root"))
+	chs := make([]format.Node, recovery.ManifestWidth)
+	for i := range chs {
+		lf := merkledag.NewRawNode([]byte{byte(i), byte(i >> 8)})
+		ch := merkledag.NodeWithData(nil)
+		require.NoError(t, ch.AddNodeLink("leaf", lf))
+		require.NoError(t, nd.AddNodeLink("child", ch))
+		require.NoError(t, dag.AddMany(ctx, []format.Node{ch, lf}))
+		chs[i] = ch
+	}
+	require.NoError(t, dag.Add(ctx, nd))
+
+	mn, err := recovery.EncodeSidecar(ctx, dag, reedsolomon.NewEncoder(dag), nd, 1)
+	require.NoError(t, err)
+	assert.Len(t, mn.Links(), 2)
+	for _, l := range mn.Links() {
+		assert.Empty(t, l.Name)
+	}
+
+	mf := recovery.NewManifests(ds)
+	require.NoError(t, mf.Register(ctx, dag, mn))
+
+	for _, id := range []cid.Cid{nd.Cid(), chs[0].Cid(), chs[len(chs)-1].Cid()} {
+		rid, ok, err := mf.Get(id)
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		rn, ok := mustGet(t, dag, rid).(recovery.Node)
+		require.True(t, ok)
+		assert.Equal(t, mustGet(t, dag, id).Links()[0].Cid, rn.Links()[0].Cid)
+	}
+}
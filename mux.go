@@ -0,0 +1,33 @@
+package recovery
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ipfs/go-cid"
+	format "github.com/ipfs/go-ipld-format"
+)
+
+// Recoverers multiplexes Recoverers by codecs of recovery Nodes they support,
+// allowing to recover DAGs encoded with different algorithms.
+type Recoverers map[uint64]Recoverer
+
+func (rs Recoverers) Recover(ctx context.Context, nd Node, ids ...cid.Cid) (<-chan *format.NodeOption, error) {
+	r, ok := rs[nd.Cid().Type()]
+	if !ok {
+		return nil, fmt.Errorf("recovery: no Recoverer for codec %d", nd.Cid().Type())
+	}
+
+	return r.Recover(ctx, nd, ids...)
+}
+
+// Close closes all the Recoverers, returning the first error encountered.
+func (rs Recoverers) Close() (err error) {
+	for _, r := range rs {
+		if cerr := r.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+
+	return
+}
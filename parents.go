@@ -0,0 +1,237 @@
+package recovery
+
+import (
+	"container/list"
+	"context"
+	"sync"
+
+	"github.com/ipfs/go-cid"
+	format "github.com/ipfs/go-ipld-format"
+	metrics "github.com/ipfs/go-metrics-interface"
+)
+
+// DefaultParentsLimit is the default amount of recovery Nodes cached by the session.
+const DefaultParentsLimit = 1024
+
+// ParentPicker chooses the recovery Node to recover the Node with the given id from.
+// It is called only when the Node is linked from multiple recovery Nodes.
+type ParentPicker func(id cid.Cid, prnts []Node) Node
+
+// PickFirst picks the most recently added recovery Node.
+func PickFirst(_ cid.Cid, prnts []Node) Node {
+	return prnts[0]
+}
+
+// PickMostRecoverable picks the recovery Node with the highest Recoverability.
+func PickMostRecoverable(_ cid.Cid, prnts []Node) Node {
+	best := prnts[0]
+	for _, prnt := range prnts[1:] {
+		if prnt.Recoverability() > best.Recoverability() {
+			best = prnt
+		}
+	}
+
+	return best
+}
+
+// parents is an LRU cache of recovery Nodes with a reverse index from their linked Nodes.
+type parents struct {
+	limit int
+
+	lru   *list.List
+	prnts map[cid.Cid]*list.Element
+	chlds map[cid.Cid][]cid.Cid
+	l     sync.Mutex
+
+	hits  metrics.Counter
+	total metrics.Counter
+}
+
+func newParents(ctx context.Context, limit int) *parents {
+	return &parents{
+		limit: limit,
+		lru:   list.New(),
+		prnts: make(map[cid.Cid]*list.Element),
+		chlds: make(map[cid.Cid][]cid.Cid),
+		hits:  metrics.NewCtx(ctx, "parents.hits_total", "Number of recovery Node lookup hits").Counter(),
+		total: metrics.NewCtx(ctx, "parents.lookups_total", "Total number of recovery Node lookups").Counter(),
+	}
+}
+
+// Add caches the recovery Node and indexes its links, evicting the least recently used Node if the limit is reached.
+func (p *parents) Add(nd Node) {
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	if e, ok := p.prnts[nd.Cid()]; ok {
+		e.Value = nd
+		p.lru.MoveToFront(e)
+		return
+	}
+
+	p.prnts[nd.Cid()] = p.lru.PushFront(nd)
+	for _, l := range nd.Links() {
+		ids := p.chlds[l.Cid]
+		if len(ids) > 0 && ids[len(ids)-1].Equals(nd.Cid()) {
+			continue // the same Node may be linked multiple times
+		}
+
+		p.chlds[l.Cid] = append(ids, nd.Cid())
+	}
+
+	for p.limit > 0 && p.lru.Len() > p.limit {
+		p.evict(p.lru.Back().Value.(Node))
+	}
+}
+
+// Get returns all cached recovery Nodes linking the given id, the most recently added first.
+func (p *parents) Get(id cid.Cid) []Node {
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	p.total.Inc()
+	ids := p.chlds[id]
+	if len(ids) == 0 {
+		return nil
+	}
+	p.hits.Inc()
+
+	nds := make([]Node, len(ids))
+	for i, id := range ids {
+		e := p.prnts[id]
+		p.lru.MoveToFront(e)
+		nds[len(ids)-1-i] = e.Value.(Node) // latest are added to the end
+	}
+
+	return nds
+}
+
+// Parent returns the cached recovery Node by its own id.
+func (p *parents) Parent(id cid.Cid) Node {
+	p.l.Lock()
+	defer p.l.Unlock()
+
+	e, ok := p.prnts[id]
+	if !ok {
+		return nil
+	}
+
+	p.lru.MoveToFront(e)
+	return e.Value.(Node)
+}
+
+// Len returns amount of cached recovery Nodes.
+func (p *parents) Len() int {
+	p.l.Lock()
+	defer p.l.Unlock()
+	return p.lru.Len()
+}
+
+func (p *parents) evict(nd Node) {
+	p.lru.Remove(p.prnts[nd.Cid()])
+	delete(p.prnts, nd.Cid())
+
+	for _, l := range nd.Links() {
+		ids := p.chlds[l.Cid][:0]
+		for _, id := range p.chlds[l.Cid] {
+			if !id.Equals(nd.Cid()) {
+				ids = append(ids, id)
+			}
+		}
+
+		if len(ids) == 0 {
+			delete(p.chlds, l.Cid)
+		} else {
+			p.chlds[l.Cid] = ids
+		}
+	}
+}
+
+// origins is an LRU cache of links of Nodes gotten within the session, which are not recovery ones.
+// It allows to find recovery Nodes kept apart from such Nodes, e.g. with KeepOriginals or EncodeSidecar, once their
+// children are missing.
+type origins struct {
+	limit int
+
+	lru   *list.List
+	nds   map[cid.Cid]*list.Element
+	chlds map[cid.Cid][]cid.Cid
+	l     sync.Mutex
+}
+
+// origin is a Node with its links only.
+type origin struct {
+	id  cid.Cid
+	ids []cid.Cid
+}
+
+func newOrigins(limit int) *origins {
+	return &origins{
+		limit: limit,
+		lru:   list.New(),
+		nds:   make(map[cid.Cid]*list.Element),
+		chlds: make(map[cid.Cid][]cid.Cid),
+	}
+}
+
+// Add indexes links of the Node, evicting the least recently used Node if the limit is reached.
+func (o *origins) Add(nd format.Node) {
+	o.l.Lock()
+	defer o.l.Unlock()
+
+	if e, ok := o.nds[nd.Cid()]; ok {
+		o.lru.MoveToFront(e)
+		return
+	}
+
+	org := &origin{id: nd.Cid(), ids: make([]cid.Cid, 0, len(nd.Links()))}
+	for _, l := range nd.Links() {
+		ids := o.chlds[l.Cid]
+		if len(ids) > 0 && ids[len(ids)-1].Equals(nd.Cid()) {
+			continue // the same Node may be linked multiple times
+		}
+
+		o.chlds[l.Cid] = append(ids, nd.Cid())
+		org.ids = append(org.ids, l.Cid)
+	}
+	o.nds[nd.Cid()] = o.lru.PushFront(org)
+
+	for o.limit > 0 && o.lru.Len() > o.limit {
+		o.evict(o.lru.Back().Value.(*origin))
+	}
+}
+
+// Get returns ids of all cached Nodes linking the given id, the most recently added first.
+func (o *origins) Get(id cid.Cid) []cid.Cid {
+	o.l.Lock()
+	defer o.l.Unlock()
+
+	ids := o.chlds[id]
+	out := make([]cid.Cid, len(ids))
+	for i, id := range ids {
+		o.lru.MoveToFront(o.nds[id])
+		out[len(ids)-1-i] = id // latest are added to the end
+	}
+
+	return out
+}
+
+func (o *origins) evict(org *origin) {
+	o.lru.Remove(o.nds[org.id])
+	delete(o.nds, org.id)
+
+	for _, ch := range org.ids {
+		ids := o.chlds[ch][:0]
+		for _, id := range o.chlds[ch] {
+			if !id.Equals(org.id) {
+				ids = append(ids, id)
+			}
+		}
+
+		if len(ids) == 0 {
+			delete(o.chlds, ch)
+		} else {
+			o.chlds[ch] = ids
+		}
+	}
+}
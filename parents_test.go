@@ -0,0 +1,93 @@
+package recovery
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-cid"
+	format "github.com/ipfs/go-ipld-format"
+	"github.com/ipfs/go-merkledag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testNode struct {
+	*merkledag.ProtoNode
+	r Recoverability
+}
+
+func (n *testNode) Recoverability() Recoverability {
+	return n.r
+}
+
+func (n *testNode) RecoveryLinks() []*format.Link {
+	return nil
+}
+
+func (n *testNode) Proto() *merkledag.ProtoNode {
+	return n.ProtoNode
+}
+
+func newTestNode(data string, r Recoverability, chs ...format.Node) *testNode {
+	nd := merkledag.NodeWithData([]byte(data))
+	for _, ch := range chs {
+		nd.AddNodeLink("", ch)
+	}
+
+	return &testNode{ProtoNode: nd, r: r}
+}
+
+func TestParents(t *testing.T) {
+	ch1 := merkledag.NewRawNode([]byte("1"))
+	ch2 := merkledag.NewRawNode([]byte("2"))
+	ch3 := merkledag.NewRawNode([]byte("3"))
+	p1 := newTestNode("p1", 1, ch1, ch2)
+	p2 := newTestNode("p2", 3, ch2)
+	p3 := newTestNode("p3", 2, ch3)
+
+	ps := newParents(context.Background(), 2)
+	ps.Add(p1)
+	ps.Add(p2)
+
+	assert.Equal(t, []Node{p1}, ps.Get(ch1.Cid()))
+	assert.Equal(t, []Node{p2, p1}, ps.Get(ch2.Cid()))
+	assert.Nil(t, ps.Get(ch3.Cid()))
+
+	// p1 is used recently, so p2 is evicted
+	ps.Get(ch1.Cid())
+	ps.Add(p3)
+	assert.Equal(t, 2, ps.Len())
+	assert.Nil(t, ps.Parent(p2.Cid()))
+	assert.Equal(t, []Node{p1}, ps.Get(ch2.Cid()))
+	assert.Equal(t, []Node{p3}, ps.Get(ch3.Cid()))
+}
+
+func TestParentPickers(t *testing.T) {
+	ch := merkledag.NewRawNode([]byte("1"))
+	p1 := newTestNode("p1", 1, ch)
+	p2 := newTestNode("p2", 3, ch)
+	p3 := newTestNode("p3", 2, ch)
+
+	prnts := []Node{p1, p2, p3}
+	require.Equal(t, p1, PickFirst(ch.Cid(), prnts))
+	require.Equal(t, p2, PickMostRecoverable(ch.Cid(), prnts))
+}
+
+func TestOrigins(t *testing.T) {
+	ch1 := merkledag.NewRawNode([]byte("1"))
+	ch2 := merkledag.NewRawNode([]byte("2"))
+	o1 := newTestNode("o1", 0, ch1, ch2)
+	o2 := newTestNode("o2", 0, ch2)
+	o3 := newTestNode("o3", 0, ch1)
+
+	orgs := newOrigins(2)
+	orgs.Add(o1)
+	orgs.Add(o2)
+	assert.Equal(t, []cid.Cid{o2.Cid(), o1.Cid()}, orgs.Get(ch2.Cid()))
+
+	// o1 is used recently, so o2 is evicted
+	orgs.Get(ch1.Cid())
+	orgs.Add(o3)
+	assert.Equal(t, []cid.Cid{o1.Cid()}, orgs.Get(ch2.Cid()))
+	assert.Equal(t, []cid.Cid{o3.Cid(), o1.Cid()}, orgs.Get(ch1.Cid()))
+}
@@ -0,0 +1,20 @@
+package recovery
+
+// Policy defines the order in which missing Nodes are recovered and fetched from the network.
+type Policy string
+
+const (
+
+	// Recover Nodes first and fetch them from the network only if recovery is not possible.
+	// Saves bandwidth, but waits for full reconstruction even if a peer has the Node.
+	RecoverFirst Policy = "recover-first"
+
+	// Fetch Nodes from the network first and recover them only if fetching fails.
+	// Blocking exchanges like Bitswap never report a Node as missing, so fetching is bounded by the network timeout,
+	// see WithNetworkTimeout.
+	NetworkFirst Policy = "network-first"
+
+	// Recover and fetch Nodes in parallel, taking whichever finishes first and cancelling the other.
+	// Lowest latency, but potentially does redundant work.
+	Race Policy = "race"
+)
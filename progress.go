@@ -0,0 +1,53 @@
+package recovery
+
+import (
+	"github.com/ipfs/go-cid"
+)
+
+// EncodeEventType defines kinds of EncodeDAG progress events.
+type EncodeEventType int
+
+const (
+	// Node is fetched and its subtree is about to be encoded.
+	NodeVisited EncodeEventType = iota
+
+	// Node is encoded to a recovery Node.
+	NodeEncoded
+
+	// Node has no links, so there is nothing to encode.
+	LeafSkipped
+
+	// Subtree is encoded previously and is skipped due to Checkpoint.
+	SubtreeSkipped
+
+	// Nodes left without redundancy are encoded under the anchor Node.
+	AnchorEncoded
+)
+
+// EncodeEvent reports EncodeDAG progress.
+type EncodeEvent struct {
+	Type EncodeEventType
+
+	// Cid of the original Node.
+	Cid cid.Cid
+
+	// Encoded is CID of the recovery Node. Set for NodeEncoded, SubtreeSkipped and AnchorEncoded.
+	Encoded cid.Cid
+
+	// Recoverability chosen for the Node. Set for NodeEncoded and AnchorEncoded.
+	Recoverability Recoverability
+
+	// ParityBytes is the size of all Redundant Nodes produced. Set for NodeEncoded and AnchorEncoded.
+	ParityBytes uint64
+}
+
+// ProgressSink receives EncodeDAG progress events.
+// It must be safe for concurrent use, as subtrees may be encoded in parallel.
+type ProgressSink func(EncodeEvent)
+
+// Progress sets the ProgressSink for EncodeDAG.
+func Progress(sink ProgressSink) EncodeOption {
+	return func(o *encodeOptions) {
+		o.progress = sink
+	}
+}
@@ -0,0 +1,98 @@
+package recovery
+
+import (
+	"math"
+
+	"github.com/ipfs/go-merkledag"
+	"github.com/ipfs/go-unixfs"
+	upb "github.com/ipfs/go-unixfs/pb"
+
+	format "github.com/ipfs/go-ipld-format"
+)
+
+// RecoverabilityFunc chooses Recoverability for the Node at the given depth of the DAG, where the root is at zero.
+// Non-positive Recoverability leaves the Node unencoded.
+// The Node passed is the original one, so e.g. its Size reports the size of the whole subtree.
+type RecoverabilityFunc func(nd format.Node, depth int) Recoverability
+
+// RecoverabilityPolicy sets the RecoverabilityFunc for EncodeDAG instead of applying the same Recoverability
+// for every Node. Chosen Recoverability is recorded in recovery Nodes, so Audit reports it.
+func RecoverabilityPolicy(f RecoverabilityFunc) EncodeOption {
+	return func(o *encodeOptions) {
+		o.rf = f
+	}
+}
+
+// ByDepth chooses Recoverability by depth of the Node, the last one is used for all the deeper Nodes.
+func ByDepth(rs ...Recoverability) RecoverabilityFunc {
+	return func(_ format.Node, depth int) Recoverability {
+		if len(rs) == 0 {
+			return 0
+		}
+		if depth >= len(rs) {
+			return rs[len(rs)-1]
+		}
+
+		return rs[depth]
+	}
+}
+
+// ByUnixFSType chooses Recoverability by UnixFS type of the Node, `def` is used for unknown types and non-UnixFS Nodes.
+func ByUnixFSType(rs map[upb.Data_DataType]Recoverability, def Recoverability) RecoverabilityFunc {
+	return func(nd format.Node, _ int) Recoverability {
+		pnd, ok := nd.(*merkledag.ProtoNode)
+		if !ok {
+			return def
+		}
+
+		fsn, err := unixfs.FSNodeFromBytes(pnd.Data())
+		if err != nil {
+			return def
+		}
+
+		r, ok := rs[fsn.Type()]
+		if !ok {
+			return def
+		}
+
+		return r
+	}
+}
+
+// ByOverhead chooses Recoverability as a ratio of parity to data, e.g. 0.5 produces one Redundant Node per two linked
+// Nodes. The result is bounded by min and max.
+func ByOverhead(ratio float64, min, max Recoverability) RecoverabilityFunc {
+	return func(nd format.Node, _ int) Recoverability {
+		return bound(int(math.Ceil(ratio*float64(len(nd.Links())))), min, max)
+	}
+}
+
+// ByLossTolerance chooses Recoverability to tolerate loss of the given fraction of all Nodes, both linked and
+// redundant, e.g. 0.2 preserves recoverability when one of every five Nodes is lost. The fraction must be below 1.
+// The result is bounded by min and max.
+func ByLossTolerance(fraction float64, min, max Recoverability) RecoverabilityFunc {
+	return func(nd format.Node, _ int) Recoverability {
+		if fraction >= 1 {
+			return bound(max, min, max)
+		}
+
+		// r / (k + r) >= f  =>  r >= f * k / (1 - f)
+		k := float64(len(nd.Links()))
+		return bound(int(math.Ceil(fraction*k/(1-fraction)-1e-9)), min, max)
+	}
+}
+
+// bound restricts Recoverability to [min, max], while keeping it positive.
+func bound(r, min, max Recoverability) Recoverability {
+	if min < 1 {
+		min = 1
+	}
+	if r < min {
+		r = min
+	}
+	if max >= min && r > max {
+		r = max
+	}
+
+	return r
+}
@@ -0,0 +1,60 @@
+package recovery
+
+import (
+	"testing"
+
+	format "github.com/ipfs/go-ipld-format"
+	"github.com/ipfs/go-merkledag"
+	"github.com/ipfs/go-unixfs"
+	upb "github.com/ipfs/go-unixfs/pb"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestByDepth(t *testing.T) {
+	f := ByDepth(3, 2, 1)
+	nd := merkledag.NodeWithData(nil)
+	assert.Equal(t, 3, f(nd, 0))
+	assert.Equal(t, 2, f(nd, 1))
+	assert.Equal(t, 1, f(nd, 2))
+	assert.Equal(t, 1, f(nd, 10))
+	assert.Equal(t, 0, ByDepth()(nd, 0))
+}
+
+func TestByUnixFSType(t *testing.T) {
+	f := ByUnixFSType(map[upb.Data_DataType]Recoverability{
+		upb.Data_Directory: 5,
+		upb.Data_HAMTShard: 4,
+	}, 1)
+
+	assert.Equal(t, 5, f(unixfs.EmptyDirNode(), 0))
+	assert.Equal(t, 1, f(merkledag.NodeWithData(unixfs.FilePBData([]byte("data"), 4)), 0))
+	assert.Equal(t, 1, f(merkledag.NodeWithData([]byte("not unixfs")), 0))
+	assert.Equal(t, 1, f(merkledag.NewRawNode([]byte("raw")), 0))
+}
+
+func TestByOverhead(t *testing.T) {
+	f := ByOverhead(0.25, 1, 10)
+	assert.Equal(t, 1, f(nodeWithLinks(1), 0))
+	assert.Equal(t, 1, f(nodeWithLinks(4), 0))
+	assert.Equal(t, 2, f(nodeWithLinks(5), 0))
+	assert.Equal(t, 10, f(nodeWithLinks(174), 0))
+}
+
+func TestByLossTolerance(t *testing.T) {
+	f := ByLossTolerance(0.2, 0, 100)
+	assert.Equal(t, 1, f(nodeWithLinks(1), 0))
+	assert.Equal(t, 1, f(nodeWithLinks(4), 0))
+	assert.Equal(t, 2, f(nodeWithLinks(5), 0))
+	assert.Equal(t, 44, f(nodeWithLinks(174), 0))
+	assert.Equal(t, 100, ByLossTolerance(1, 0, 100)(nodeWithLinks(1), 0))
+	assert.Equal(t, 3, ByLossTolerance(1, 3, 0)(nodeWithLinks(1), 0))
+	assert.Equal(t, 1, ByLossTolerance(1, 0, 0)(nodeWithLinks(1), 0))
+}
+
+func nodeWithLinks(n int) format.Node {
+	nd := merkledag.NodeWithData(nil)
+	for i := 0; i < n; i++ {
+		nd.AddNodeLink("", merkledag.NewRawNode([]byte{byte(i)}))
+	}
+	return nd
+}
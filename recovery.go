@@ -2,6 +2,8 @@ package recovery
 
 import (
 	"context"
+	"io"
+	"time"
 
 	"github.com/ipfs/go-cid"
 	format "github.com/ipfs/go-ipld-format"
@@ -29,15 +31,34 @@ type Node interface {
 	Proto() *merkledag.ProtoNode
 }
 
+// Unwrap returns the Node the recovery Node was encoded from.
+// Recovery Nodes able to wrap Nodes other than ProtoNode expose them with `Inner() format.Node` method.
+func Unwrap(nd Node) format.Node {
+	if in, ok := nd.(interface{ Inner() format.Node }); ok {
+		return in.Inner()
+	}
+
+	return nd.Proto()
+}
+
 type Recoverer interface {
+	// Close stops all ongoing recoveries and releases resources held by the Recoverer.
+	// Recover must not be called after Close.
+	io.Closer
 
 	// Recovers Nodes by ids from the recovery Node.
 	Recover(context.Context, Node, ...cid.Cid) (<-chan *format.NodeOption, error)
 }
 
+// DefaultIdleTimeout is the time Recoverers with bounded recovery sessions stop a session having no pending requests
+// after, unless other idle timeout is given. Otherwise, sessions fetching Nodes which are never found would hold their
+// slots forever.
+const DefaultIdleTimeout = 10 * time.Second
+
 type Encoder interface {
 
 	// Encodes Node to a recovery Node.
+	// The original Node is left in place, so the caller decides when it is safe to remove it.
 	// Bigger recoverability - higher storage usage.
 	Encode(context.Context, format.Node, Recoverability) (Node, error)
 }
@@ -2,6 +2,7 @@ package reedsolomon
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/ipfs/go-ipld-format"
 	"github.com/ipfs/go-merkledag"
@@ -15,17 +16,29 @@ import (
 // Encode applies Reed-Solomon coding on the given IPLD Node promoting it to a recovery Node.
 // Use `r` to specify needed amount of generated recovery Nodes.
 func Encode(ctx context.Context, dag format.DAGService, nd format.Node, r recovery.Recoverability) (*Node, error) {
+	rd, err := encodePadded(ctx, dag, nd, r)
+	if err != nil {
+		return nil, err
+	}
+
+	return rd, dag.Remove(ctx, nd.Cid()) // there is no need to keep original
+}
+
+// encodePadded is Encode leaving the original Node in place.
+func encodePadded(ctx context.Context, dag format.DAGService, nd format.Node, r recovery.Recoverability) (*Node, error) {
 	rd, err := NewNode(nd)
 	if err != nil {
 		return nil, err
 	}
 
 	nds, s := make([]format.Node, len(rd.Links())), 0
+	rd.lengths = make([]uint64, len(rd.Links()))
 	for i, l := range rd.Links() {
 		nds[i], err = l.GetNode(ctx, dag)
 		if err != nil {
 			return nil, err
 		}
+		rd.lengths[i] = uint64(len(nds[i].RawData()))
 
 		if len(nds[i].RawData()) > s { // finding the largest child
 			s = len(nds[i].RawData())
@@ -33,41 +46,111 @@ func Encode(ctx context.Context, dag format.DAGService, nd format.Node, r recove
 	}
 
 	s += varint.UvarintSize(uint64(s))
-	ln := len(rd.Links())
-	bs := make([][]byte, ln+r)
+	bs := make([][]byte, len(nds))
 	for i := range bs {
 		bs[i] = make([]byte, s)
-		if i < ln {
-			l := len(nds[i].RawData())
-			n := varint.PutUvarint(bs[i], uint64(l))
-			copy(bs[i][n:], nds[i].RawData())
-		}
+		n := varint.PutUvarint(bs[i], uint64(len(nds[i].RawData())))
+		copy(bs[i][n:], nds[i].RawData())
 	}
 
-	rs, err := reedsolomon.New(ln, r)
+	return rd, encode(ctx, dag, rd, bs, r)
+}
+
+// EncodeStriped is like Encode, but instead of padding every child to the largest one, it packs children data one
+// after another into stripes of the given size, so the size of Redundant Nodes tracks total size of children rather
+// than the largest one. Offsets of the children are recorded in the Node as their lengths.
+// Recoverability is counted in stripes then, so a lost child consumes as much of it as many stripes it spans.
+// Non-positive stripe size chooses the one producing as many stripes as there are children.
+func EncodeStriped(ctx context.Context, dag format.DAGService, nd format.Node, r recovery.Recoverability, stripe int) (*Node, error) {
+	rd, err := encodeStriped(ctx, dag, nd, r, stripe)
 	if err != nil {
 		return nil, err
 	}
 
-	err = rs.Encode(bs)
+	return rd, dag.Remove(ctx, nd.Cid()) // there is no need to keep original
+}
+
+// encodeStriped is EncodeStriped leaving the original Node in place.
+func encodeStriped(ctx context.Context, dag format.DAGService, nd format.Node, r recovery.Recoverability, stripe int) (*Node, error) {
+	rd, err := NewNode(nd)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, b := range bs[ln:] {
-		rnd := merkledag.NewRawNode(b)
-		err = dag.Add(ctx, rnd)
+	nds, t := make([]format.Node, len(rd.Links())), 0
+	rd.lengths = make([]uint64, len(rd.Links()))
+	for i, l := range rd.Links() {
+		nds[i], err = l.GetNode(ctx, dag)
 		if err != nil {
 			return nil, err
 		}
 
-		rd.AddRedundantNode(rnd)
+		rd.lengths[i] = uint64(len(nds[i].RawData()))
+		t += len(nds[i].RawData())
 	}
 
-	err = dag.Add(ctx, rd)
-	if err != nil {
-		return nil, err
+	if stripe <= 0 && len(nds) > 0 {
+		stripe = (t + len(nds) - 1) / len(nds)
+	}
+	if stripe <= 0 {
+		stripe = 1
 	}
+	rd.stripe = uint64(stripe)
 
-	return rd, dag.Remove(ctx, nd.Cid()) // there is no need to keep original
+	bs := make([][]byte, stripes(rd.lengths, stripe))
+	for i := range bs {
+		bs[i] = make([]byte, stripe)
+	}
+
+	off := 0
+	for _, nd := range nds {
+		for n := 0; n < len(nd.RawData()); {
+			pos := off + n
+			n += copy(bs[pos/stripe][pos%stripe:], nd.RawData()[n:])
+		}
+		off += len(nd.RawData())
+	}
+
+	return rd, encode(ctx, dag, rd, bs, r)
+}
+
+// encode computes `r` Redundant Nodes for every group of data vectors and stores them along with the recovery Node.
+// Data vectors are split into groups only if there are too many of them to be encoded at once.
+func encode(ctx context.Context, dag format.DAGService, rd *Node, data [][]byte, r int) error {
+	if len(data) == 0 {
+		return fmt.Errorf("reedsolomon: Node must have links")
+	}
+
+	rd.version, rd.shard, rd.data, rd.parity = Version, uint64(len(data[0])), uint32(len(data)), uint32(r)
+	rd.group = uint64(groupSize(len(data), r))
+	for g, gn := 0, groups(len(data), int(rd.group)); g < gn; g++ {
+		start, end := groupBounds(g, len(data), int(rd.group))
+		rs, err := reedsolomon.New(end-start, r)
+		if err != nil {
+			return err
+		}
+
+		bs := make([][]byte, end-start, end-start+r)
+		copy(bs, data[start:end])
+		for i := 0; i < r; i++ {
+			bs = append(bs, make([]byte, len(data[0])))
+		}
+
+		err = rs.Encode(bs)
+		if err != nil {
+			return err
+		}
+
+		for _, b := range bs[end-start:] {
+			rnd := merkledag.NewRawNode(b)
+			err = dag.Add(ctx, rnd)
+			if err != nil {
+				return err
+			}
+
+			rd.AddRedundantNode(rnd)
+		}
+	}
+
+	return dag.Add(ctx, rd)
 }
@@ -28,3 +28,26 @@ func TestEncode(t *testing.T) {
 		assert.NotNil(t, r)
 	}
 }
+
+func TestEncodeStriped(t *testing.T) {
+	ctx := context.Background()
+	dag := dstest.Mock()
+
+	in := merkledag.NodeWithData([]byte("1234567890"))
+	big := merkledag.NewRawNode(make([]byte, 1024))
+	small := merkledag.NewRawNode([]byte("12345"))
+	small2 := merkledag.NewRawNode([]byte("67890"))
+	in.AddNodeLink("link", big)
+	in.AddNodeLink("link", small)
+	in.AddNodeLink("link", small2)
+	dag.AddMany(ctx, []format.Node{in, big, small, small2})
+
+	nd, err := EncodeStriped(ctx, dag, in, 2, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, nd.Recoverability())
+	assert.EqualValues(t, 345, nd.Stripe())
+	assert.Equal(t, []uint64{1024, 5, 5}, nd.Lengths())
+	for _, r := range nd.RecoveryLinks() {
+		assert.Less(t, r.Size, uint64(len(big.RawData())))
+	}
+}
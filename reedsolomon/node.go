@@ -8,44 +8,294 @@ import (
 	"github.com/ipfs/go-cid"
 	"github.com/ipfs/go-ipld-format"
 	"github.com/ipfs/go-merkledag"
+	mh "github.com/multiformats/go-multihash"
 
 	"github.com/Wondertan/go-ipfs-recovery"
 	cpb "github.com/Wondertan/go-ipfs-recovery/reedsolomon/pb"
 )
 
+// Version of the Node format produced by encoding. Nodes are written with the lowest version describing features they
+// use, so older readers still decode Nodes not using newer ones.
+// Version zero Nodes do not describe themselves, so their parameters are inferred from links.
+// Version two Nodes wrap non-ProtoNodes.
+// Version three Nodes record CID prefix of the wrapped Node, as it differs from the one inferred by older readers.
+const Version = 3
+
+// Algorithm is the coding algorithm recorded in the Node: Reed-Solomon over GF(2^8) with a Cauchy matrix.
+const Algorithm = "reedsolomon-cauchy-gf8"
+
 // Node is a recovery Node based ob Reed-Solomon coding.
 type Node struct {
 	*merkledag.ProtoNode
 
 	recovery []*format.Link
+	stripe   uint64
+	lengths  []uint64
+	group    uint64
 	cache    []byte
 	cid      cid.Cid
 	builder  cid.Builder
+
+	version      uint32
+	shard        uint64
+	data, parity uint32
+
+	// prefix of the wrapped Node CID, restored on decoding.
+	prefix cid.Prefix
+
+	// codec and raw data of the wrapped non-ProtoNode, the ProtoNode keeps its links only.
+	codec uint64
+	raw   []byte
+	inner format.Node
 }
 
+// NewNode wraps the Node into a recovery one. Nodes of codecs other than dag-pb, e.g. dag-cbor, are embedded with
+// their codec and bytes, while their links are exposed through the Node. Note that links of such Nodes can't be
+// altered through the recovery Node.
 func NewNode(nd format.Node) (*Node, error) {
 	pnd, ok := nd.(*merkledag.ProtoNode)
-	if !ok {
-		return nil, fmt.Errorf("reedsolomon: Node must be proto")
+	if ok {
+		rnd := &Node{ProtoNode: pnd.Copy().(*merkledag.ProtoNode), prefix: pnd.Cid().Prefix()}
+		rnd.SetCidBuilder(pnd.CidBuilder())
+		return rnd, nil
+	}
+
+	if nd.Cid().Type() == Codec {
+		return nil, fmt.Errorf("reedsolomon: Node is recovery already")
 	}
 
-	rnd := &Node{ProtoNode: pnd.Copy().(*merkledag.ProtoNode)}
-	rnd.SetCidBuilder(pnd.CidBuilder())
+	rnd := &Node{
+		ProtoNode: new(merkledag.ProtoNode),
+		prefix:    nd.Cid().Prefix(),
+		codec:     nd.Cid().Type(),
+		raw:       nd.RawData(),
+		inner:     nd.Copy(),
+	}
+	for _, l := range nd.Links() {
+		err := rnd.ProtoNode.AddRawLink(l.Name, &format.Link{Name: l.Name, Size: l.Size, Cid: l.Cid})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rnd.SetCidBuilder(nd.Cid().Prefix())
 	return rnd, nil
 }
 
+// Proto returns the ProtoNode the recovery Node was encoded from.
+// For wrapped non-ProtoNodes it only keeps their links, use Inner to get them.
 func (n *Node) Proto() *merkledag.ProtoNode {
 	return n.ProtoNode
 }
 
+// Inner returns the Node the recovery Node was encoded from, either ProtoNode or wrapped Node of another codec.
+func (n *Node) Inner() format.Node {
+	if n.raw == nil {
+		return n.ProtoNode
+	}
+	if n.inner == nil {
+		err := n.decodeInner()
+		if err != nil {
+			panic(fmt.Sprintf("can't decode inner Node: %s", err))
+		}
+	}
+
+	return n.inner
+}
+
+// decodeInner decodes the wrapped Node with its recorded prefix. Nodes not recording it have CIDs built as the one
+// of the recovery Node, but with the inner codec.
+func (n *Node) decodeInner() error {
+	if n.raw == nil || n.builder == nil {
+		return nil
+	}
+
+	var b cid.Builder = n.prefix
+	if n.prefix == (cid.Prefix{}) {
+		b = n.builder.WithCodec(n.codec)
+	}
+
+	id, err := b.Sum(n.raw)
+	if err != nil {
+		return err
+	}
+
+	blk, err := blocks.NewBlockWithCid(n.raw, id)
+	if err != nil {
+		return err
+	}
+
+	n.inner, err = format.Decode(blk)
+	return err
+}
+
+func (n *Node) Resolve(path []string) (interface{}, []string, error) {
+	return n.Inner().Resolve(path)
+}
+
+func (n *Node) Tree(path string, depth int) []string {
+	return n.Inner().Tree(path, depth)
+}
+
+func (n *Node) ResolveLink(path []string) (*format.Link, []string, error) {
+	return n.Inner().ResolveLink(path)
+}
+
 func (n *Node) Recoverability() recovery.Recoverability {
-	return len(n.recovery)
+	if n.version > 0 {
+		return int(n.parity)
+	}
+
+	return len(n.recovery) / groups(n.vectors(), int(n.group))
 }
 
 func (n *Node) RecoveryLinks() []*format.Link {
 	return n.recovery
 }
 
+// Stripe returns size of stripes children data is packed into, or zero if every child is padded to the largest one.
+func (n *Node) Stripe() uint64 {
+	return n.stripe
+}
+
+// Lengths returns sizes of children data, in the order of links.
+// Version zero Nodes record them only if children are packed into stripes.
+func (n *Node) Lengths() []uint64 {
+	return n.lengths
+}
+
+// Group returns amount of data vectors encoded together, or zero if all of them are encoded as a single group.
+// Redundant Nodes are ordered by groups, every group has Recoverability of them.
+func (n *Node) Group() uint64 {
+	return n.group
+}
+
+// Version returns format version of the Node.
+func (n *Node) Version() uint32 {
+	switch {
+	case n.version == 0:
+		return 0
+	case n.ownPrefix():
+		return 3
+	case n.raw != nil:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// ownPrefix reports whether the CID prefix of the wrapped Node can't be inferred, so it has to be recorded.
+// Wrapped ProtoNodes are inferred to be CIDv0, while other Nodes get the prefix of the recovery Node with their codec.
+func (n *Node) ownPrefix() bool {
+	switch {
+	case n.prefix == (cid.Prefix{}):
+		return false
+	case n.raw == nil:
+		return !samePrefix(n.prefix, merkledag.V0CidPrefix())
+	}
+
+	p, ok := n.builder.(cid.Prefix)
+	return !ok || !samePrefix(n.prefix, p.WithCodec(n.codec).(cid.Prefix))
+}
+
+// samePrefix checks whether the prefixes build the same CIDs, default multihash length is the same as explicit one.
+func samePrefix(a, b cid.Prefix) bool {
+	length := func(p cid.Prefix) int {
+		if l, ok := mh.DefaultLengths[p.MhType]; ok && p.MhLength == -1 {
+			return l
+		}
+
+		return p.MhLength
+	}
+
+	return a.Version == b.Version && a.Codec == b.Codec && a.MhType == b.MhType && length(a) == length(b)
+}
+
+// ShardSize returns size of every data and parity vector the coding is applied on.
+func (n *Node) ShardSize() uint64 {
+	if n.version > 0 || len(n.recovery) == 0 {
+		return n.shard
+	}
+
+	return n.recovery[0].Size
+}
+
+// Vectors counts distinct data vectors spanned by the children at the given link positions, i.e. how much of
+// Recoverability their loss consumes. Children packed into stripes may span multiple vectors or share them.
+func (n *Node) Vectors(links ...int) int {
+	return len(n.spanned(links))
+}
+
+// Margin returns the amount of vectors which can be lost additionally in every group preserving recoverability, given
+// positions of missing children and Redundant Nodes. Groups are coded independently, so the least one is returned.
+func (n *Node) Margin(data, parity []int) int {
+	dln, r := n.vectors(), n.Recoverability()
+	gn := groups(dln, int(n.group))
+	lost := make([]int, gn)
+	for v := range n.spanned(data) {
+		if n.group > 0 {
+			lost[v/int(n.group)]++
+		} else {
+			lost[0]++
+		}
+	}
+	for _, i := range parity {
+		if r > 0 && i/r < gn {
+			lost[i/r]++
+		}
+	}
+
+	m := r
+	for _, l := range lost {
+		if r-l < m {
+			m = r - l
+		}
+	}
+
+	return m
+}
+
+// spanned returns data vectors spanned by the children at the given link positions.
+func (n *Node) spanned(links []int) map[int]struct{} {
+	vs := make(map[int]struct{}, len(links))
+	if n.stripe == 0 {
+		for _, i := range links {
+			vs[i] = struct{}{}
+		}
+
+		return vs
+	}
+
+	offs, off := make([]uint64, len(n.lengths)), uint64(0)
+	for i, l := range n.lengths {
+		offs[i], off = off, off+l
+	}
+
+	for _, i := range links {
+		if i < 0 || i >= len(n.lengths) || n.lengths[i] == 0 {
+			continue
+		}
+
+		for v := offs[i] / n.stripe; v <= (offs[i]+n.lengths[i]-1)/n.stripe; v++ {
+			vs[int(v)] = struct{}{}
+		}
+	}
+
+	return vs
+}
+
+// vectors counts data vectors, either children or stripes.
+func (n *Node) vectors() int {
+	if n.version > 0 {
+		return int(n.data)
+	}
+	if n.stripe > 0 {
+		return stripes(n.lengths, int(n.stripe))
+	}
+
+	return len(n.Links())
+}
+
 func (n *Node) AddRedundantNode(nd format.Node) {
 	if nd == nil {
 		return
@@ -109,7 +359,17 @@ func (n *Node) String() string {
 func (n *Node) Copy() format.Node {
 	nd := new(Node)
 	nd.ProtoNode = n.ProtoNode.Copy().(*merkledag.ProtoNode)
-	nd.builder = n.builder
+	nd.builder, nd.prefix = n.builder, n.prefix
+	nd.stripe, nd.group = n.stripe, n.group
+	nd.version, nd.shard, nd.data, nd.parity = n.version, n.shard, n.data, n.parity
+	nd.codec, nd.raw = n.codec, n.raw
+	if n.inner != nil {
+		nd.inner = n.inner.Copy()
+	}
+	if n.lengths != nil {
+		nd.lengths = make([]uint64, len(n.lengths))
+		copy(nd.lengths, n.lengths)
+	}
 	l := len(n.recovery)
 	if l > 0 {
 		nd.recovery = make([]*format.Link, l)
@@ -154,7 +414,24 @@ func (n *Node) Size() (uint64, error) {
 
 func MarshalNode(n *Node) ([]byte, error) {
 	var err error
-	pb := &cpb.PBNode{}
+	pb := &cpb.PBNode{
+		Stripe:  n.stripe,
+		Lengths: n.lengths,
+		Group:   n.group,
+		Version: n.Version(),
+		Shard:   n.shard,
+		Data:    n.data,
+		Parity:  n.parity,
+	}
+	if n.version > 0 {
+		pb.Algorithm = Algorithm
+	}
+	if n.raw != nil {
+		pb.Codec, pb.Inner = n.codec, n.raw
+	}
+	if n.ownPrefix() {
+		pb.Prefix = n.prefix.Bytes()
+	}
 	pb.Proto, err = n.ProtoNode.Marshal()
 	if err != nil {
 		return nil, err
@@ -188,6 +465,26 @@ func UnmarshalNode(data []byte) (*Node, error) {
 	if err != nil {
 		return nil, err
 	}
+	switch {
+	case pb.Version > Version:
+		return nil, fmt.Errorf("reedsolomon: unsupported Node format version %d", pb.Version)
+	case pb.Version > 0 && pb.Algorithm != Algorithm:
+		return nil, fmt.Errorf("reedsolomon: unsupported algorithm %s", pb.Algorithm)
+	}
+	nd.stripe, nd.lengths, nd.group = pb.Stripe, pb.Lengths, pb.Group
+	nd.version, nd.shard, nd.data, nd.parity = pb.Version, pb.Shard, pb.Data, pb.Parity
+	if pb.Inner != nil {
+		nd.codec, nd.raw = pb.Codec, pb.Inner
+	}
+	if pb.Prefix != nil {
+		nd.prefix, err = cid.PrefixFromBytes(pb.Prefix)
+		if err != nil {
+			return nil, err
+		}
+		if nd.raw == nil {
+			nd.ProtoNode.SetCidBuilder(nd.prefix)
+		}
+	}
 
 	l := len(pb.Recovery)
 	if l > 0 {
@@ -222,6 +519,11 @@ func DecodeNode(b blocks.Block) (format.Node, error) {
 
 	nd.cid = b.Cid()
 	nd.SetCidBuilder(b.Cid().Prefix())
+	err = nd.decodeInner()
+	if err != nil {
+		return nil, err
+	}
+
 	return nd, nil
 }
 
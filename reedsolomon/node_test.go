@@ -1,12 +1,18 @@
 package reedsolomon
 
 import (
+	"context"
 	"testing"
 
+	cbor "github.com/ipfs/go-ipld-cbor"
 	"github.com/ipfs/go-ipld-format"
 	"github.com/ipfs/go-merkledag"
+	dstest "github.com/ipfs/go-merkledag/test"
+	mh "github.com/multiformats/go-multihash"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	cpb "github.com/Wondertan/go-ipfs-recovery/reedsolomon/pb"
 )
 
 func TestNodeRedundant(t *testing.T) {
@@ -41,6 +47,25 @@ func TestNodeMarshalUnmarshal(t *testing.T) {
 	assert.Equal(t, pdata, out.Data())
 }
 
+func TestNodeMarshalUnmarshalStriped(t *testing.T) {
+	in, err := NewNode(merkledag.NodeWithData([]byte("1234567890")))
+	require.NoError(t, err)
+	in.AddRedundantNode(merkledag.NewRawNode([]byte("12345")))
+	in.stripe, in.lengths = 5, []uint64{3, 7}
+
+	data, err := MarshalNode(in)
+	require.NoError(t, err)
+
+	out, err := UnmarshalNode(data)
+	require.NoError(t, err)
+	assert.EqualValues(t, 5, out.Stripe())
+	assert.Equal(t, []uint64{3, 7}, out.Lengths())
+
+	cp := out.Copy().(*Node)
+	out.lengths[0] = 0
+	assert.Equal(t, []uint64{3, 7}, cp.Lengths())
+}
+
 func TestNodeDecode(t *testing.T) {
 	in, err := NewNode(merkledag.NodeWithData([]byte("1234567890")))
 	require.NoError(t, err)
@@ -81,3 +106,79 @@ func TestNode_Copy(t *testing.T) {
 	assert.NotNil(t, cp.(*Node).RecoveryLinks())
 	assert.NotNil(t, cp.(*Node).Data())
 }
+
+func TestNodeVersion(t *testing.T) {
+	ctx := context.Background()
+	dag := dstest.Mock()
+
+	prnt := merkledag.NodeWithData([]byte("1234567890"))
+	ch1 := merkledag.NodeWithData([]byte("03243423423423"))
+	ch2 := merkledag.NodeWithData([]byte("123450"))
+	prnt.AddNodeLink("link", ch1)
+	prnt.AddNodeLink("link", ch2)
+	dag.AddMany(ctx, []format.Node{prnt, ch1, ch2})
+
+	enc, err := Encode(ctx, dag, prnt, 2)
+	require.NoError(t, err)
+
+	out, err := UnmarshalNode(enc.RawData())
+	require.NoError(t, err)
+	assert.EqualValues(t, 1, out.Version()) // no features of later versions are used
+	assert.EqualValues(t, len(ch1.RawData())+1, out.ShardSize()) // the largest child with its length prefix
+	assert.Equal(t, []uint64{uint64(len(ch1.RawData())), uint64(len(ch2.RawData()))}, out.Lengths())
+	assert.Equal(t, 2, out.Recoverability())
+
+	// Nodes encoded before versioning still decode and recover
+	legacy := enc.Copy().(*Node)
+	legacy.version, legacy.shard, legacy.data, legacy.parity, legacy.lengths = 0, 0, 0, 0, nil
+	out, err = UnmarshalNode(legacy.RawData())
+	require.NoError(t, err)
+	assert.Zero(t, out.Version())
+	assert.EqualValues(t, len(ch1.RawData())+1, out.ShardSize())
+	assert.Equal(t, 2, out.Recoverability())
+
+	out.SetCidBuilder(enc.CidBuilder())
+	sh, err := newShards(out)
+	require.NoError(t, err)
+	for _, l := range out.RecoveryLinks() {
+		rnd, err := dag.Get(ctx, l.Cid)
+		require.NoError(t, err)
+		sh.Fill(rnd)
+	}
+	nd, err := sh.Get(ch1.Cid())
+	require.NoError(t, err)
+	assert.Equal(t, ch1.RawData(), nd.RawData())
+
+	// CID prefix of the wrapped ProtoNode can't be inferred for CIDv1
+	v1 := prnt.Copy().(*merkledag.ProtoNode)
+	v1.SetCidBuilder(merkledag.V1CidPrefix())
+	enc, err = Encode(ctx, dag, v1, 2)
+	require.NoError(t, err)
+	out, err = UnmarshalNode(enc.RawData())
+	require.NoError(t, err)
+	assert.EqualValues(t, Version, out.Version())
+
+	// wrapped non-ProtoNodes get their CID prefix from the recovery Node
+	cnd, err := cbor.WrapObject(map[string]interface{}{"a": ch1.Cid()}, mh.SHA2_256, -1)
+	require.NoError(t, err)
+	require.NoError(t, dag.Add(ctx, cnd))
+	enc, err = Encode(ctx, dag, cnd, 1)
+	require.NoError(t, err)
+	rnd, err := DecodeNode(enc)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, rnd.(*Node).Version())
+	assert.True(t, cnd.Cid().Equals(rnd.(*Node).Inner().Cid()))
+
+	// unknown formats are rejected
+	pb := &cpb.PBNode{Version: Version + 1}
+	data, err := pb.Marshal()
+	require.NoError(t, err)
+	_, err = UnmarshalNode(data)
+	assert.Error(t, err)
+
+	pb = &cpb.PBNode{Version: Version, Algorithm: "unknown"}
+	data, err = pb.Marshal()
+	require.NoError(t, err)
+	_, err = UnmarshalNode(data)
+	assert.Error(t, err)
+}
@@ -26,8 +26,19 @@ var _ = math.Inf
 const _ = proto.GoGoProtoPackageIsVersion3 // please upgrade the proto package
 
 type PBNode struct {
-	Proto    []byte    `protobuf:"bytes,1,opt,name=proto,proto3" json:"proto,omitempty"`
-	Recovery []*PBLink `protobuf:"bytes,2,rep,name=recovery,proto3" json:"recovery,omitempty"`
+	Proto     []byte    `protobuf:"bytes,1,opt,name=proto,proto3" json:"proto,omitempty"`
+	Recovery  []*PBLink `protobuf:"bytes,2,rep,name=recovery,proto3" json:"recovery,omitempty"`
+	Stripe    uint64    `protobuf:"varint,3,opt,name=stripe,proto3" json:"stripe,omitempty"`
+	Lengths   []uint64  `protobuf:"varint,4,rep,packed,name=lengths,proto3" json:"lengths,omitempty"`
+	Group     uint64    `protobuf:"varint,5,opt,name=group,proto3" json:"group,omitempty"`
+	Version   uint32    `protobuf:"varint,6,opt,name=version,proto3" json:"version,omitempty"`
+	Algorithm string    `protobuf:"bytes,7,opt,name=algorithm,proto3" json:"algorithm,omitempty"`
+	Shard     uint64    `protobuf:"varint,8,opt,name=shard,proto3" json:"shard,omitempty"`
+	Data      uint32    `protobuf:"varint,9,opt,name=data,proto3" json:"data,omitempty"`
+	Parity    uint32    `protobuf:"varint,10,opt,name=parity,proto3" json:"parity,omitempty"`
+	Codec     uint64    `protobuf:"varint,11,opt,name=codec,proto3" json:"codec,omitempty"`
+	Inner     []byte    `protobuf:"bytes,12,opt,name=inner,proto3" json:"inner,omitempty"`
+	Prefix    []byte    `protobuf:"bytes,13,opt,name=prefix,proto3" json:"prefix,omitempty"`
 }
 
 func (m *PBNode) Reset()      { *m = PBNode{} }
@@ -76,6 +87,83 @@ func (m *PBNode) GetRecovery() []*PBLink {
 	return nil
 }
 
+func (m *PBNode) GetStripe() uint64 {
+	if m != nil {
+		return m.Stripe
+	}
+	return 0
+}
+
+func (m *PBNode) GetLengths() []uint64 {
+	if m != nil {
+		return m.Lengths
+	}
+	return nil
+}
+
+func (m *PBNode) GetGroup() uint64 {
+	if m != nil {
+		return m.Group
+	}
+	return 0
+}
+
+func (m *PBNode) GetVersion() uint32 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *PBNode) GetAlgorithm() string {
+	if m != nil {
+		return m.Algorithm
+	}
+	return ""
+}
+
+func (m *PBNode) GetShard() uint64 {
+	if m != nil {
+		return m.Shard
+	}
+	return 0
+}
+
+func (m *PBNode) GetData() uint32 {
+	if m != nil {
+		return m.Data
+	}
+	return 0
+}
+
+func (m *PBNode) GetParity() uint32 {
+	if m != nil {
+		return m.Parity
+	}
+	return 0
+}
+
+func (m *PBNode) GetCodec() uint64 {
+	if m != nil {
+		return m.Codec
+	}
+	return 0
+}
+
+func (m *PBNode) GetInner() []byte {
+	if m != nil {
+		return m.Inner
+	}
+	return nil
+}
+
+func (m *PBNode) GetPrefix() []byte {
+	if m != nil {
+		return m.Prefix
+	}
+	return nil
+}
+
 type PBLink struct {
 	Hash  []byte `protobuf:"bytes,1,opt,name=Hash,proto3" json:"Hash,omitempty"`
 	Name  string `protobuf:"bytes,2,opt,name=Name,proto3" json:"Name,omitempty"`
@@ -143,22 +231,30 @@ func init() {
 func init() { proto.RegisterFile("reedsolomon/pb/readsolomon.proto", fileDescriptor_4d7ca2a520a34643) }
 
 var fileDescriptor_4d7ca2a520a34643 = []byte{
-	// 226 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0x52, 0x28, 0x4a, 0x4d, 0x4d,
-	0x29, 0xce, 0xcf, 0xc9, 0xcf, 0xcd, 0xcf, 0xd3, 0x2f, 0x48, 0xd2, 0x2f, 0x4a, 0x4d, 0x84, 0x71,
-	0xf5, 0x0a, 0x8a, 0xf2, 0x4b, 0xf2, 0x85, 0xb8, 0x8b, 0x52, 0x93, 0xf3, 0xcb, 0x52, 0x8b, 0x2a,
-	0xf5, 0x0a, 0x92, 0x94, 0xfc, 0xb9, 0xd8, 0x02, 0x9c, 0xfc, 0xf2, 0x53, 0x52, 0x85, 0x44, 0xb8,
-	0x58, 0xc1, 0xf2, 0x12, 0x8c, 0x0a, 0x8c, 0x1a, 0x3c, 0x41, 0x10, 0x8e, 0x90, 0x3e, 0x17, 0x07,
-	0x4c, 0xb9, 0x04, 0x93, 0x02, 0xb3, 0x06, 0xb7, 0x91, 0xb0, 0x1e, 0x92, 0x7e, 0xbd, 0x00, 0x27,
-	0x9f, 0xcc, 0xbc, 0xec, 0x20, 0xb8, 0x22, 0x25, 0x17, 0x90, 0x81, 0x20, 0x31, 0x21, 0x21, 0x2e,
-	0x16, 0x8f, 0xc4, 0xe2, 0x0c, 0xa8, 0x79, 0x60, 0x36, 0x48, 0xcc, 0x2f, 0x31, 0x37, 0x55, 0x82,
-	0x49, 0x81, 0x51, 0x83, 0x33, 0x08, 0xcc, 0x06, 0x89, 0x05, 0x67, 0x56, 0xa5, 0x4a, 0x30, 0x2b,
-	0x30, 0x6a, 0xb0, 0x04, 0x81, 0xd9, 0x4e, 0x26, 0x17, 0x1e, 0xca, 0x31, 0xdc, 0x78, 0x28, 0xc7,
-	0xf0, 0xe1, 0xa1, 0x1c, 0x63, 0xc3, 0x23, 0x39, 0xc6, 0x15, 0x8f, 0xe4, 0x18, 0x4f, 0x3c, 0x92,
-	0x63, 0xbc, 0xf0, 0x48, 0x8e, 0xf1, 0xc1, 0x23, 0x39, 0xc6, 0x17, 0x8f, 0xe4, 0x18, 0x3e, 0x3c,
-	0x92, 0x63, 0x9c, 0xf0, 0x58, 0x8e, 0xe1, 0xc2, 0x63, 0x39, 0x86, 0x1b, 0x8f, 0xe5, 0x18, 0x92,
-	0xd8, 0xc0, 0x6e, 0x36, 0x06, 0x04, 0x00, 0x00, 0xff, 0xff, 0xad, 0xa0, 0x5a, 0x12, 0x04, 0x01,
-	0x00, 0x00,
+	// 361 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x4c, 0x91, 0x31, 0x6e, 0xe2, 0x40,
+	0x18, 0x85, 0x3d, 0x60, 0x0c, 0x0c, 0xd0, 0xcc, 0xae, 0x56, 0x7f, 0xb1, 0x1a, 0x59, 0x54, 0xae,
+	0x8c, 0xb4, 0x9b, 0x13, 0xa0, 0x14, 0x29, 0x22, 0x84, 0x9c, 0x13, 0x0c, 0x78, 0x82, 0x47, 0x01,
+	0x8f, 0x35, 0x76, 0x50, 0x48, 0x95, 0x23, 0xe4, 0x18, 0x39, 0x4a, 0x4a, 0x9a, 0x48, 0x94, 0xc1,
+	0x34, 0x29, 0x39, 0x42, 0x34, 0xbf, 0xed, 0x24, 0xdd, 0xfb, 0x9e, 0x9e, 0xdf, 0xfc, 0x7a, 0xa6,
+	0xbe, 0x91, 0x32, 0xce, 0xf5, 0x5a, 0x6f, 0x74, 0x3a, 0xc9, 0x16, 0x13, 0x23, 0x45, 0x83, 0x61,
+	0x66, 0x74, 0xa1, 0xd9, 0xc0, 0xc8, 0xa5, 0xde, 0x4a, 0xb3, 0x0b, 0xb3, 0xc5, 0xf8, 0xad, 0x45,
+	0xbd, 0xf9, 0x74, 0xa6, 0x63, 0xc9, 0x7e, 0xd3, 0x0e, 0x06, 0x80, 0xf8, 0x24, 0x18, 0x46, 0x15,
+	0xb0, 0x09, 0xed, 0x35, 0x79, 0x68, 0xf9, 0xed, 0x60, 0xf0, 0xef, 0x57, 0xf8, 0xa3, 0x20, 0x9c,
+	0x4f, 0xaf, 0x55, 0x7a, 0x17, 0x7d, 0x85, 0xd8, 0x1f, 0xea, 0xe5, 0x85, 0x51, 0x99, 0x84, 0xb6,
+	0x4f, 0x02, 0x37, 0xaa, 0x89, 0x01, 0xed, 0xae, 0x65, 0xba, 0x2a, 0x92, 0x1c, 0x5c, 0xbf, 0x1d,
+	0xb8, 0x51, 0x83, 0xf6, 0xe1, 0x95, 0xd1, 0xf7, 0x19, 0x74, 0xf0, 0x83, 0x0a, 0x6c, 0x7e, 0x2b,
+	0x4d, 0xae, 0x74, 0x0a, 0x9e, 0x4f, 0x82, 0x51, 0xd4, 0x20, 0xfb, 0x4b, 0xfb, 0x62, 0xbd, 0xd2,
+	0x46, 0x15, 0xc9, 0x06, 0xba, 0x3e, 0x09, 0xfa, 0xd1, 0xb7, 0x61, 0xdb, 0xf2, 0x44, 0x98, 0x18,
+	0x7a, 0x55, 0x1b, 0x02, 0x63, 0xd4, 0x8d, 0x45, 0x21, 0xa0, 0x8f, 0x55, 0xa8, 0xed, 0xa5, 0x99,
+	0x30, 0xaa, 0xd8, 0x01, 0x45, 0xb7, 0x26, 0xdb, 0xb0, 0xd4, 0xb1, 0x5c, 0xc2, 0xa0, 0x6a, 0x40,
+	0xb0, 0xae, 0x4a, 0x53, 0x69, 0x60, 0x58, 0xcd, 0x83, 0x80, 0x1d, 0x46, 0xde, 0xaa, 0x07, 0x18,
+	0xa1, 0x5d, 0xd3, 0xf8, 0x92, 0x7a, 0xd5, 0x32, 0xf6, 0xe5, 0x2b, 0x91, 0x27, 0xf5, 0xaa, 0xa8,
+	0xad, 0x37, 0x13, 0x1b, 0x09, 0x2d, 0x3c, 0x1e, 0xb5, 0xf5, 0x6e, 0xd4, 0x63, 0xb3, 0x1a, 0xea,
+	0xe9, 0xc5, 0xfe, 0xc8, 0x9d, 0xc3, 0x91, 0x3b, 0xe7, 0x23, 0x27, 0x4f, 0x25, 0x27, 0x2f, 0x25,
+	0x27, 0xaf, 0x25, 0x27, 0xfb, 0x92, 0x93, 0xf7, 0x92, 0x93, 0x8f, 0x92, 0x3b, 0xe7, 0x92, 0x93,
+	0xe7, 0x13, 0x77, 0xf6, 0x27, 0xee, 0x1c, 0x4e, 0xdc, 0x59, 0x78, 0xf8, 0xe7, 0xfe, 0x7f, 0x0e,
+	0x00, 0x3d, 0x39, 0xd2, 0xaa, 0x0b, 0x02, 0x00, 0x00,
 }
 
 func (this *PBNode) Equal(that interface{}) bool {
@@ -191,6 +287,44 @@ func (this *PBNode) Equal(that interface{}) bool {
 			return false
 		}
 	}
+	if this.Stripe != that1.Stripe {
+		return false
+	}
+	if len(this.Lengths) != len(that1.Lengths) {
+		return false
+	}
+	for i := range this.Lengths {
+		if this.Lengths[i] != that1.Lengths[i] {
+			return false
+		}
+	}
+	if this.Group != that1.Group {
+		return false
+	}
+	if this.Version != that1.Version {
+		return false
+	}
+	if this.Algorithm != that1.Algorithm {
+		return false
+	}
+	if this.Shard != that1.Shard {
+		return false
+	}
+	if this.Data != that1.Data {
+		return false
+	}
+	if this.Parity != that1.Parity {
+		return false
+	}
+	if this.Codec != that1.Codec {
+		return false
+	}
+	if !bytes.Equal(this.Inner, that1.Inner) {
+		return false
+	}
+	if !bytes.Equal(this.Prefix, that1.Prefix) {
+		return false
+	}
 	return true
 }
 func (this *PBLink) Equal(that interface{}) bool {
@@ -227,12 +361,23 @@ func (this *PBNode) GoString() string {
 	if this == nil {
 		return "nil"
 	}
-	s := make([]string, 0, 6)
+	s := make([]string, 0, 17)
 	s = append(s, "&recovery_pb.PBNode{")
 	s = append(s, "Proto: "+fmt.Sprintf("%#v", this.Proto)+",\n")
 	if this.Recovery != nil {
 		s = append(s, "Recovery: "+fmt.Sprintf("%#v", this.Recovery)+",\n")
 	}
+	s = append(s, "Stripe: "+fmt.Sprintf("%#v", this.Stripe)+",\n")
+	s = append(s, "Lengths: "+fmt.Sprintf("%#v", this.Lengths)+",\n")
+	s = append(s, "Group: "+fmt.Sprintf("%#v", this.Group)+",\n")
+	s = append(s, "Version: "+fmt.Sprintf("%#v", this.Version)+",\n")
+	s = append(s, "Algorithm: "+fmt.Sprintf("%#v", this.Algorithm)+",\n")
+	s = append(s, "Shard: "+fmt.Sprintf("%#v", this.Shard)+",\n")
+	s = append(s, "Data: "+fmt.Sprintf("%#v", this.Data)+",\n")
+	s = append(s, "Parity: "+fmt.Sprintf("%#v", this.Parity)+",\n")
+	s = append(s, "Codec: "+fmt.Sprintf("%#v", this.Codec)+",\n")
+	s = append(s, "Inner: "+fmt.Sprintf("%#v", this.Inner)+",\n")
+	s = append(s, "Prefix: "+fmt.Sprintf("%#v", this.Prefix)+",\n")
 	s = append(s, "}")
 	return strings.Join(s, "")
 }
@@ -276,6 +421,80 @@ func (m *PBNode) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if len(m.Prefix) > 0 {
+		i -= len(m.Prefix)
+		copy(dAtA[i:], m.Prefix)
+		i = encodeVarintReadsolomon(dAtA, i, uint64(len(m.Prefix)))
+		i--
+		dAtA[i] = 0x6a
+	}
+	if len(m.Inner) > 0 {
+		i -= len(m.Inner)
+		copy(dAtA[i:], m.Inner)
+		i = encodeVarintReadsolomon(dAtA, i, uint64(len(m.Inner)))
+		i--
+		dAtA[i] = 0x62
+	}
+	if m.Codec != 0 {
+		i = encodeVarintReadsolomon(dAtA, i, uint64(m.Codec))
+		i--
+		dAtA[i] = 0x58
+	}
+	if m.Parity != 0 {
+		i = encodeVarintReadsolomon(dAtA, i, uint64(m.Parity))
+		i--
+		dAtA[i] = 0x50
+	}
+	if m.Data != 0 {
+		i = encodeVarintReadsolomon(dAtA, i, uint64(m.Data))
+		i--
+		dAtA[i] = 0x48
+	}
+	if m.Shard != 0 {
+		i = encodeVarintReadsolomon(dAtA, i, uint64(m.Shard))
+		i--
+		dAtA[i] = 0x40
+	}
+	if len(m.Algorithm) > 0 {
+		i -= len(m.Algorithm)
+		copy(dAtA[i:], m.Algorithm)
+		i = encodeVarintReadsolomon(dAtA, i, uint64(len(m.Algorithm)))
+		i--
+		dAtA[i] = 0x3a
+	}
+	if m.Version != 0 {
+		i = encodeVarintReadsolomon(dAtA, i, uint64(m.Version))
+		i--
+		dAtA[i] = 0x30
+	}
+	if m.Group != 0 {
+		i = encodeVarintReadsolomon(dAtA, i, uint64(m.Group))
+		i--
+		dAtA[i] = 0x28
+	}
+	if len(m.Lengths) > 0 {
+		dAtA2 := make([]byte, len(m.Lengths)*10)
+		var j1 int
+		for _, num := range m.Lengths {
+			for num >= 1<<7 {
+				dAtA2[j1] = uint8(uint64(num)&0x7f | 0x80)
+				num >>= 7
+				j1++
+			}
+			dAtA2[j1] = uint8(num)
+			j1++
+		}
+		i -= j1
+		copy(dAtA[i:], dAtA2[:j1])
+		i = encodeVarintReadsolomon(dAtA, i, uint64(j1))
+		i--
+		dAtA[i] = 0x22
+	}
+	if m.Stripe != 0 {
+		i = encodeVarintReadsolomon(dAtA, i, uint64(m.Stripe))
+		i--
+		dAtA[i] = 0x18
+	}
 	if len(m.Recovery) > 0 {
 		for iNdEx := len(m.Recovery) - 1; iNdEx >= 0; iNdEx-- {
 			{
@@ -369,6 +588,46 @@ func (m *PBNode) Size() (n int) {
 			n += 1 + l + sovReadsolomon(uint64(l))
 		}
 	}
+	if m.Stripe != 0 {
+		n += 1 + sovReadsolomon(uint64(m.Stripe))
+	}
+	if len(m.Lengths) > 0 {
+		l = 0
+		for _, e := range m.Lengths {
+			l += sovReadsolomon(uint64(e))
+		}
+		n += 1 + sovReadsolomon(uint64(l)) + l
+	}
+	if m.Group != 0 {
+		n += 1 + sovReadsolomon(uint64(m.Group))
+	}
+	if m.Version != 0 {
+		n += 1 + sovReadsolomon(uint64(m.Version))
+	}
+	l = len(m.Algorithm)
+	if l > 0 {
+		n += 1 + l + sovReadsolomon(uint64(l))
+	}
+	if m.Shard != 0 {
+		n += 1 + sovReadsolomon(uint64(m.Shard))
+	}
+	if m.Data != 0 {
+		n += 1 + sovReadsolomon(uint64(m.Data))
+	}
+	if m.Parity != 0 {
+		n += 1 + sovReadsolomon(uint64(m.Parity))
+	}
+	if m.Codec != 0 {
+		n += 1 + sovReadsolomon(uint64(m.Codec))
+	}
+	l = len(m.Inner)
+	if l > 0 {
+		n += 1 + l + sovReadsolomon(uint64(l))
+	}
+	l = len(m.Prefix)
+	if l > 0 {
+		n += 1 + l + sovReadsolomon(uint64(l))
+	}
 	return n
 }
 
@@ -410,6 +669,17 @@ func (this *PBNode) String() string {
 	s := strings.Join([]string{`&PBNode{`,
 		`Proto:` + fmt.Sprintf("%v", this.Proto) + `,`,
 		`Recovery:` + repeatedStringForRecovery + `,`,
+		`Stripe:` + fmt.Sprintf("%v", this.Stripe) + `,`,
+		`Lengths:` + fmt.Sprintf("%v", this.Lengths) + `,`,
+		`Group:` + fmt.Sprintf("%v", this.Group) + `,`,
+		`Version:` + fmt.Sprintf("%v", this.Version) + `,`,
+		`Algorithm:` + fmt.Sprintf("%v", this.Algorithm) + `,`,
+		`Shard:` + fmt.Sprintf("%v", this.Shard) + `,`,
+		`Data:` + fmt.Sprintf("%v", this.Data) + `,`,
+		`Parity:` + fmt.Sprintf("%v", this.Parity) + `,`,
+		`Codec:` + fmt.Sprintf("%v", this.Codec) + `,`,
+		`Inner:` + fmt.Sprintf("%v", this.Inner) + `,`,
+		`Prefix:` + fmt.Sprintf("%v", this.Prefix) + `,`,
 		`}`,
 	}, "")
 	return s
@@ -531,6 +801,315 @@ func (m *PBNode) Unmarshal(dAtA []byte) error {
 				return err
 			}
 			iNdEx = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Stripe", wireType)
+			}
+			m.Stripe = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowReadsolomon
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Stripe |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType == 0 {
+				var v uint64
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowReadsolomon
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					v |= uint64(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				m.Lengths = append(m.Lengths, v)
+			} else if wireType == 2 {
+				var packedLen int
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowReadsolomon
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					packedLen |= int(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				if packedLen < 0 {
+					return ErrInvalidLengthReadsolomon
+				}
+				postIndex := iNdEx + packedLen
+				if postIndex < 0 {
+					return ErrInvalidLengthReadsolomon
+				}
+				if postIndex > l {
+					return io.ErrUnexpectedEOF
+				}
+				var elementCount int
+				var count int
+				for _, integer := range dAtA[iNdEx:postIndex] {
+					if integer < 128 {
+						count++
+					}
+				}
+				elementCount = count
+				if elementCount != 0 && len(m.Lengths) == 0 {
+					m.Lengths = make([]uint64, 0, elementCount)
+				}
+				for iNdEx < postIndex {
+					var v uint64
+					for shift := uint(0); ; shift += 7 {
+						if shift >= 64 {
+							return ErrIntOverflowReadsolomon
+						}
+						if iNdEx >= l {
+							return io.ErrUnexpectedEOF
+						}
+						b := dAtA[iNdEx]
+						iNdEx++
+						v |= uint64(b&0x7F) << shift
+						if b < 0x80 {
+							break
+						}
+					}
+					m.Lengths = append(m.Lengths, v)
+				}
+			} else {
+				return fmt.Errorf("proto: wrong wireType = %d for field Lengths", wireType)
+			}
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Group", wireType)
+			}
+			m.Group = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowReadsolomon
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Group |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Version", wireType)
+			}
+			m.Version = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowReadsolomon
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Version |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 7:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Algorithm", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowReadsolomon
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthReadsolomon
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthReadsolomon
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Algorithm = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 8:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Shard", wireType)
+			}
+			m.Shard = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowReadsolomon
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Shard |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 9:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Data", wireType)
+			}
+			m.Data = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowReadsolomon
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Data |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 10:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Parity", wireType)
+			}
+			m.Parity = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowReadsolomon
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Parity |= uint32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 11:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Codec", wireType)
+			}
+			m.Codec = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowReadsolomon
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Codec |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 12:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Inner", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowReadsolomon
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthReadsolomon
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthReadsolomon
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Inner = append(m.Inner[:0], dAtA[iNdEx:postIndex]...)
+			if m.Inner == nil {
+				m.Inner = []byte{}
+			}
+			iNdEx = postIndex
+		case 13:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Prefix", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowReadsolomon
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthReadsolomon
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthReadsolomon
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Prefix = append(m.Prefix[:0], dAtA[iNdEx:postIndex]...)
+			if m.Prefix == nil {
+				m.Prefix = []byte{}
+			}
+			iNdEx = postIndex
 		default:
 			iNdEx = preIndex
 			skippy, err := skipReadsolomon(dAtA[iNdEx:])
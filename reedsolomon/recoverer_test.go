@@ -3,15 +3,19 @@ package reedsolomon
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/ipfs/go-blockservice"
+	"github.com/ipfs/go-cid"
 	"github.com/ipfs/go-datastore"
 	"github.com/ipfs/go-datastore/sync"
 	blockstore "github.com/ipfs/go-ipfs-blockstore"
 	offline "github.com/ipfs/go-ipfs-exchange-offline"
+	cbor "github.com/ipfs/go-ipld-cbor"
 	format "github.com/ipfs/go-ipld-format"
 	"github.com/ipfs/go-merkledag"
 	dstest "github.com/ipfs/go-merkledag/test"
+	mh "github.com/multiformats/go-multihash"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -51,6 +55,67 @@ func TestRecoverer(t *testing.T) {
 	assert.Equal(t, ch3.RawData(), no.Node.RawData())
 }
 
+// stallingDAG never closes GetMany channel until the context is done, like the network does.
+type stallingDAG struct {
+	format.DAGService
+}
+
+func (d *stallingDAG) GetMany(ctx context.Context, ids []cid.Cid) <-chan *format.NodeOption {
+	out := make(chan *format.NodeOption)
+	go func() {
+		defer close(out)
+		for no := range d.DAGService.GetMany(ctx, ids) {
+			select {
+			case out <- no:
+			case <-ctx.Done():
+				return
+			}
+		}
+		<-ctx.Done()
+	}()
+	return out
+}
+
+func TestRecovererStreaming(t *testing.T) {
+	ctx := context.Background()
+	rctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	dag := dstest.Mock()
+	rec := NewRecoverer(rctx, &stallingDAG{dag}, recovery.Requested)
+
+	prnt := merkledag.NodeWithData([]byte("1234567890"))
+	ch1 := merkledag.NodeWithData([]byte("03243423423423"))
+	ch2 := merkledag.NodeWithData([]byte("123450"))
+	ch3 := merkledag.NodeWithData([]byte("1234509876"))
+	prnt.AddNodeLink("link", ch1)
+	prnt.AddNodeLink("link", ch2)
+	prnt.AddNodeLink("link", ch3)
+	dag.AddMany(ctx, []format.Node{prnt, ch1, ch2, ch3})
+
+	enc, err := Encode(ctx, dag, prnt, 1)
+	require.NoError(t, err)
+
+	dag.Remove(ctx, ch1.Cid())
+	dag.Remove(ctx, ch3.Cid())
+
+	// session can't finish, as recovery is not possible, but available Node should be still responded.
+	out, err := rec.Recover(ctx, enc, ch2.Cid(), ch1.Cid())
+	require.NoError(t, err)
+
+	select {
+	case no := <-out:
+		require.NoError(t, no.Err)
+		assert.Equal(t, ch2.RawData(), no.Node.RawData())
+	case <-time.After(time.Second):
+		t.Fatal("available Node is not responded")
+	}
+
+	cancel()
+	no := <-out
+	assert.Error(t, no.Err)
+}
+
 func TestRecovererUnixFS(t *testing.T) {
 	ctx := context.Background()
 
@@ -89,3 +154,323 @@ func TestRecovererUnixFS(t *testing.T) {
 
 	root.Validate()
 }
+
+func TestRecovererWide(t *testing.T) {
+	ctx := context.Background()
+	dag := dstest.Mock()
+	rec := NewRecoverer(ctx, dag, recovery.Requested)
+
+	prnt := merkledag.NodeWithData([]byte("1234567890"))
+	chs := make([]format.Node, 300)
+	for i := range chs {
+		chs[i] = merkledag.NewRawNode([]byte{byte(i), byte(i >> 8)})
+		prnt.AddNodeLink("link", chs[i])
+	}
+	dag.AddMany(ctx, append(chs, prnt))
+
+	enc, err := Encode(ctx, dag, prnt, 2)
+	require.NoError(t, err)
+	assert.EqualValues(t, 150, enc.Group())
+	assert.Len(t, enc.RecoveryLinks(), 4)
+	assert.Equal(t, 2, enc.Recoverability())
+
+	// two children are lost in every group
+	lost := []format.Node{chs[0], chs[149], chs[150], chs[299]}
+	for _, ch := range lost {
+		dag.Remove(ctx, ch.Cid())
+	}
+
+	out, err := rec.Recover(ctx, enc, cids(lost)...)
+	require.NoError(t, err)
+
+	got := make(map[cid.Cid][]byte)
+	for range lost {
+		no := <-out
+		require.NoError(t, no.Err)
+		got[no.Node.Cid()] = no.Node.RawData()
+	}
+	for _, ch := range lost {
+		assert.Equal(t, ch.RawData(), got[ch.Cid()])
+	}
+}
+
+func TestRecovererWideGroups(t *testing.T) {
+	ctx := context.Background()
+	rctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	dag := dstest.Mock()
+	rec := NewRecoverer(rctx, &stallingDAG{dag}, recovery.Requested)
+
+	prnt := merkledag.NodeWithData([]byte("1234567890"))
+	chs := make([]format.Node, 300)
+	for i := range chs {
+		chs[i] = merkledag.NewRawNode([]byte{byte(i), byte(i >> 8)})
+		prnt.AddNodeLink("link", chs[i])
+	}
+	dag.AddMany(ctx, append(chs, prnt))
+
+	enc, err := Encode(ctx, dag, prnt, 2)
+	require.NoError(t, err)
+
+	// the first group is beyond recovery, but the second one is not
+	lost := []format.Node{chs[0], chs[1], chs[2], chs[299]}
+	for _, ch := range lost {
+		dag.Remove(ctx, ch.Cid())
+	}
+
+	out, err := rec.Recover(ctx, enc, chs[299].Cid())
+	require.NoError(t, err)
+
+	select {
+	case no := <-out:
+		require.NoError(t, no.Err)
+		assert.Equal(t, chs[299].RawData(), no.Node.RawData())
+	case <-time.After(time.Second):
+		t.Fatal("Node of the recoverable group is not responded")
+	}
+}
+
+func cids(nds []format.Node) []cid.Cid {
+	ids := make([]cid.Cid, len(nds))
+	for i, nd := range nds {
+		ids[i] = nd.Cid()
+	}
+	return ids
+}
+
+func TestRecovererCbor(t *testing.T) {
+	ctx := context.Background()
+	dag := dstest.Mock()
+	rec := NewRecoverer(ctx, dag, recovery.Requested)
+
+	ch1 := merkledag.NewRawNode([]byte("03243423423423"))
+	ch2 := merkledag.NewRawNode([]byte("123450"))
+	ch3 := merkledag.NewRawNode([]byte("1234509876"))
+	prnt, err := cbor.WrapObject(map[string]interface{}{
+		"a": ch1.Cid(),
+		"b": []interface{}{ch2.Cid(), ch3.Cid()},
+	}, mh.SHA2_256, -1)
+	require.NoError(t, err)
+	dag.AddMany(ctx, []format.Node{prnt, ch1, ch2, ch3})
+
+	enc, err := Encode(ctx, dag, prnt, 2)
+	require.NoError(t, err)
+	assert.Len(t, enc.Links(), 3)
+
+	nd, err := format.Decode(enc)
+	require.NoError(t, err)
+	assert.Equal(t, prnt.RawData(), recovery.Unwrap(nd.(recovery.Node)).RawData())
+	assert.True(t, prnt.Cid().Equals(recovery.Unwrap(nd.(recovery.Node)).Cid()))
+
+	v, _, err := nd.Resolve([]string{"b", "1"})
+	require.NoError(t, err)
+	assert.True(t, ch3.Cid().Equals(v.(*format.Link).Cid))
+
+	dag.Remove(ctx, ch1.Cid())
+	dag.Remove(ctx, ch3.Cid())
+
+	out, err := rec.Recover(ctx, nd.(recovery.Node), ch1.Cid(), ch3.Cid())
+	require.NoError(t, err)
+
+	got := make(map[cid.Cid][]byte)
+	for i := 0; i < 2; i++ {
+		no := <-out
+		require.NoError(t, no.Err)
+		got[no.Node.Cid()] = no.Node.RawData()
+	}
+	assert.Equal(t, ch1.RawData(), got[ch1.Cid()])
+	assert.Equal(t, ch3.RawData(), got[ch3.Cid()])
+}
+
+// unrecoverable encodes a Node with three children and removes two of them, so its recovery never completes.
+func unrecoverable(t *testing.T, dag format.DAGService, data string) (enc *Node, has, lost format.Node) {
+	ctx := context.Background()
+	prnt := merkledag.NodeWithData([]byte(data))
+	ch1 := merkledag.NodeWithData([]byte(data + "03243423423423"))
+	ch2 := merkledag.NodeWithData([]byte(data + "123450"))
+	ch3 := merkledag.NodeWithData([]byte(data + "1234509876"))
+	prnt.AddNodeLink("link", ch1)
+	prnt.AddNodeLink("link", ch2)
+	prnt.AddNodeLink("link", ch3)
+	dag.AddMany(ctx, []format.Node{prnt, ch1, ch2, ch3})
+
+	enc, err := Encode(ctx, dag, prnt, 1)
+	require.NoError(t, err)
+
+	dag.Remove(ctx, ch1.Cid())
+	dag.Remove(ctx, ch3.Cid())
+	return enc, ch2, ch1
+}
+
+func TestRecovererClose(t *testing.T) {
+	ctx := context.Background()
+	dag := dstest.Mock()
+	rec := NewRecoverer(ctx, &stallingDAG{dag}, recovery.Requested)
+
+	enc, _, lost := unrecoverable(t, dag, "1234567890")
+	out, err := rec.Recover(ctx, enc, lost.Cid())
+	require.NoError(t, err)
+
+	require.NoError(t, rec.Close())
+	select {
+	case no := <-out:
+		assert.Error(t, no.Err)
+	default:
+		t.Fatal("pending request is not responded on Close")
+	}
+	assert.Zero(t, rec.(*recoverer).Sessions())
+
+	_, err = rec.Recover(ctx, enc, lost.Cid())
+	assert.Error(t, err)
+}
+
+func TestRecovererIdleTimeout(t *testing.T) {
+	ctx := context.Background()
+	dag := dstest.Mock()
+	rec := NewRecoverer(ctx, &stallingDAG{dag}, recovery.All, IdleTimeout(50*time.Millisecond))
+	defer rec.Close()
+
+	enc, has, _ := unrecoverable(t, dag, "1234567890")
+	out, err := rec.Recover(ctx, enc, has.Cid())
+	require.NoError(t, err)
+
+	no := <-out
+	require.NoError(t, no.Err)
+	assert.Equal(t, has.RawData(), no.Node.RawData())
+
+	// the request is fully served, so the stalled session is evicted after being idle.
+	r := rec.(*recoverer)
+	assert.Eventually(t, func() bool {
+		return r.Sessions() == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestRecovererMaxSessions(t *testing.T) {
+	ctx := context.Background()
+	dag := dstest.Mock()
+	rec := NewRecoverer(ctx, &stallingDAG{dag}, recovery.Requested, MaxSessions(1))
+	defer rec.Close()
+
+	enc1, _, lost1 := unrecoverable(t, dag, "1234567890")
+	enc2, has2, _ := unrecoverable(t, dag, "0987654321")
+
+	ctx1, cancel1 := context.WithCancel(ctx)
+	defer cancel1()
+	_, err := rec.Recover(ctx1, enc1, lost1.Cid())
+	require.NoError(t, err)
+
+	// the only session slot is taken, so the second recovery waits for it.
+	ctx2, cancel2 := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel2()
+	_, err = rec.Recover(ctx2, enc2, has2.Cid())
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	// the first session ends with its only request, freeing the slot.
+	cancel1()
+	out2, err := rec.Recover(ctx, enc2, has2.Cid())
+	require.NoError(t, err)
+	no := <-out2
+	require.NoError(t, no.Err)
+	assert.Equal(t, has2.RawData(), no.Node.RawData())
+}
+
+func TestRecovererCancel(t *testing.T) {
+	ctx := context.Background()
+	dag := dstest.Mock()
+	rec := NewRecoverer(ctx, &stallingDAG{dag}, recovery.Requested)
+	defer rec.Close()
+	r := rec.(*recoverer)
+
+	enc, has, lost := unrecoverable(t, dag, "1234567890")
+
+	ctx1, cancel1 := context.WithCancel(ctx)
+	defer cancel1()
+	out1, err := rec.Recover(ctx1, enc, lost.Cid())
+	require.NoError(t, err)
+
+	ctx2, cancel2 := context.WithCancel(ctx)
+	defer cancel2()
+	out2, err := rec.Recover(ctx2, enc, lost.Cid(), has.Cid())
+	require.NoError(t, err)
+
+	no := <-out2
+	require.NoError(t, no.Err)
+	assert.Equal(t, has.RawData(), no.Node.RawData())
+
+	// the session creator leaves first, others are still waiting.
+	cancel1()
+	no = <-out1
+	assert.Equal(t, context.Canceled, no.Err)
+	_, ok := <-out1
+	assert.False(t, ok)
+	assert.Equal(t, 1, r.Sessions())
+
+	// the session is still alive for new waiters.
+	ctx3, cancel3 := context.WithCancel(ctx)
+	defer cancel3()
+	out3, err := rec.Recover(ctx3, enc, has.Cid(), lost.Cid())
+	require.NoError(t, err)
+	no = <-out3
+	require.NoError(t, no.Err)
+	assert.Equal(t, has.RawData(), no.Node.RawData())
+
+	cancel3()
+	no = <-out3
+	assert.Equal(t, context.Canceled, no.Err)
+	assert.Equal(t, 1, r.Sessions())
+
+	// the last waiter leaves, so the session stops.
+	cancel2()
+	no = <-out2
+	assert.Equal(t, context.Canceled, no.Err)
+	assert.Eventually(t, func() bool {
+		return r.Sessions() == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestRecovererCancelServed(t *testing.T) {
+	ctx := context.Background()
+	dag := dstest.Mock()
+	rec := NewRecoverer(ctx, &stallingDAG{dag}, recovery.Requested, MaxSessions(1))
+	defer rec.Close()
+	r := rec.(*recoverer)
+
+	enc, has, lost := unrecoverable(t, dag, "1234567890")
+
+	// fully served waiter is not waiting anymore.
+	out1, err := rec.Recover(ctx, enc, has.Cid())
+	require.NoError(t, err)
+	no := <-out1
+	require.NoError(t, no.Err)
+	_, ok := <-out1
+	assert.False(t, ok)
+
+	ctx2, cancel2 := context.WithCancel(ctx)
+	defer cancel2()
+	out2, err := rec.Recover(ctx2, enc, lost.Cid())
+	require.NoError(t, err)
+
+	// the only pending waiter leaves, so the session stops and frees its slot.
+	cancel2()
+	no = <-out2
+	assert.Equal(t, context.Canceled, no.Err)
+	_, ok = <-out2
+	assert.False(t, ok)
+	assert.Eventually(t, func() bool {
+		return r.Sessions() == 0
+	}, time.Second, 10*time.Millisecond)
+
+	enc2, has2, _ := unrecoverable(t, dag, "0987654321")
+	tctx, tcancel := context.WithTimeout(ctx, time.Second)
+	defer tcancel()
+	out3, err := rec.Recover(tctx, enc2, has2.Cid())
+	require.NoError(t, err)
+	no = <-out3
+	require.NoError(t, no.Err)
+	assert.Equal(t, has2.RawData(), no.Node.RawData())
+
+	// canceled request is not accepted.
+	_, err = rec.Recover(ctx2, enc, lost.Cid())
+	assert.Equal(t, context.Canceled, err)
+}
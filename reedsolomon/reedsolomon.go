@@ -2,6 +2,7 @@ package reedsolomon
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/ipfs/go-cid"
 	"github.com/ipfs/go-ipld-format"
@@ -26,6 +27,9 @@ func init() {
 
 type reedSolomon struct {
 	dag format.DAGService
+
+	striped bool
+	stripe  int
 }
 
 // NewEncoder creates new Reed-Solomon Encoder.
@@ -33,11 +37,68 @@ func NewEncoder(dag format.DAGService) recovery.Encoder {
 	return &reedSolomon{dag: dag}
 }
 
+// NewStripedEncoder creates new Reed-Solomon Encoder packing children into stripes of the given size.
+// See EncodeStriped.
+func NewStripedEncoder(dag format.DAGService, stripe int) recovery.Encoder {
+	return &reedSolomon{dag: dag, striped: true, stripe: stripe}
+}
+
+// Encode encodes the Node leaving the original in place, so the caller removes it once it is safe to.
 func (rs *reedSolomon) Encode(ctx context.Context, nd format.Node, r recovery.Recoverability) (recovery.Node, error) {
 	rd, ok := nd.(recovery.Node)
 	if ok {
 		return rd, nil
 	}
 
-	return Encode(ctx, rs.dag, nd, r)
+	if rs.striped {
+		return encodeStriped(ctx, rs.dag, nd, r, rs.stripe)
+	}
+
+	return encodePadded(ctx, rs.dag, nd, r)
+}
+
+func (rs *reedSolomon) String() string {
+	if rs.striped {
+		return fmt.Sprintf("reedsolomon/striped/%d", rs.stripe)
+	}
+
+	return "reedsolomon"
+}
+
+// MaxShards is the maximum amount of data and parity shards Reed-Solomon coding is applied on at once.
+// Children of wider Nodes are split into groups, each with its own Redundant Nodes.
+const MaxShards = 256
+
+// groupSize chooses the amount of data vectors in a group, so every group fits into MaxShards with its parity.
+// Zero means all data vectors are encoded as a single group.
+func groupSize(ln, r int) int {
+	if ln+r <= MaxShards || r >= MaxShards {
+		return 0
+	}
+
+	n := (ln + MaxShards - r - 1) / (MaxShards - r) // amount of groups
+	return (ln + n - 1) / n
+}
+
+// groups counts groups of data vectors.
+func groups(ln, size int) int {
+	if size <= 0 {
+		return 1
+	}
+
+	return (ln + size - 1) / size
+}
+
+// groupBounds returns the range of data vectors in the group.
+func groupBounds(g, ln, size int) (int, int) {
+	if size <= 0 {
+		return 0, ln
+	}
+
+	start, end := g*size, (g+1)*size
+	if end > ln {
+		end = ln
+	}
+
+	return start, end
 }
@@ -1,6 +1,7 @@
 package reedsolomon
 
 import (
+	"bytes"
 	"fmt"
 
 	"github.com/ipfs/go-block-format"
@@ -19,54 +20,120 @@ type shard struct {
 	i    int
 	nd   format.Node
 	want bool
+	crpt bool // excluded from reconstruction
+
+	vs      []int // vectors the shard is stored in
+	off, ln int   // position within data stripes, if striped
+}
+
+// group is a set of data vectors encoded together with its own parity vectors.
+type group struct {
+	rs  *reedsolomon.RS
+	vs  []int // global indexes of data vectors followed by parity ones
+	hvn int   // amount of available vectors
 }
 
 type shards struct {
-	rs        *reedsolomon.RS
-	id        cid.Cid
-	ids       []cid.Cid
-	m         map[cid.Cid]*shard
-	vects     [][]byte
-	hvs, wnts []int
-	lln       int
+	grps   []*group
+	gs     []int // group of every vector
+	id     cid.Cid
+	ids    []cid.Cid
+	m      map[cid.Cid]*shard
+	lln    int
+	stripe int
+
+	vects      [][]byte
+	miss       []int  // amount of shards missing for the vector to be available
+	rcs        []bool // vectors reconstructed rather than filled from shards
+	hvs, vwnts []int
+
+	wnts []int
 }
 
 func newShards(rnd *Node) (*shards, error) {
-	lln, rln, s := len(rnd.Links()), len(rnd.RecoveryLinks()), rnd.RecoveryLinks()[0].Size
-	ln := lln + rln
+	lln, rln := len(rnd.Links()), len(rnd.RecoveryLinks())
+	if rln == 0 {
+		return nil, fmt.Errorf("reedsolomon: Node has no Redundant Nodes")
+	}
 
-	rs, err := reedsolomon.New(lln, rln)
-	if err != nil {
-		return nil, err
+	s := int(rnd.ShardSize())
+	if rnd.Stripe() > 0 && len(rnd.Lengths()) != lln {
+		return nil, fmt.Errorf("reedsolomon: lengths don't match links")
+	}
+
+	dln := rnd.vectors()
+	if rnd.Stripe() > 0 && dln != stripes(rnd.Lengths(), int(rnd.Stripe())) ||
+		rnd.Stripe() == 0 && dln != lln {
+		return nil, fmt.Errorf("reedsolomon: data shards don't match links")
+	}
+	ln := dln + rln
+
+	gn, r := groups(dln, int(rnd.Group())), rnd.Recoverability()
+	if r*gn != rln {
+		return nil, fmt.Errorf("reedsolomon: Redundant Nodes don't match groups")
 	}
 
 	ss := &shards{
-		rs:    rs,
-		ids:   make([]cid.Cid, ln),
-		id:    rnd.Cid(),
-		m:     make(map[cid.Cid]*shard, ln),
-		vects: make([][]byte, ln),
-		hvs:   make([]int, 0, lln),
-		wnts:  make([]int, 0, ln),
-		lln:   lln,
+		grps:   make([]*group, gn),
+		gs:     make([]int, ln),
+		ids:    make([]cid.Cid, lln+rln),
+		id:     rnd.Cid(),
+		m:      make(map[cid.Cid]*shard, lln+rln),
+		lln:    lln,
+		stripe: int(rnd.Stripe()),
+		vects:  make([][]byte, ln),
+		miss:   make([]int, ln),
+		rcs:    make([]bool, ln),
+		hvs:    make([]int, 0, dln),
+	}
+	for i := range ss.vects {
+		ss.vects[i] = make([]byte, s)
 	}
 
+	for g := range ss.grps {
+		start, end := groupBounds(g, dln, int(rnd.Group()))
+		rs, err := reedsolomon.New(end-start, r)
+		if err != nil {
+			return nil, err
+		}
+
+		grp := &group{rs: rs}
+		for v := start; v < end; v++ {
+			grp.vs = append(grp.vs, v)
+		}
+		for v := dln + g*r; v < dln+(g+1)*r; v++ {
+			grp.vs = append(grp.vs, v)
+		}
+		for _, v := range grp.vs {
+			ss.gs[v] = g
+		}
+		ss.grps[g] = grp
+	}
+
+	off := 0
 	for i, l := range rnd.Links() {
-		ss.ids[i] = l.Cid
-		ss.vects[i] = make([]byte, s)
-		ss.m[l.Cid] = &shard{
-			ss: ss,
-			i:  i,
+		sh := &shard{ss: ss, i: i}
+		if ss.stripe > 0 {
+			sh.off, sh.ln = off, int(rnd.Lengths()[i])
+			off += sh.ln
+			for v := sh.off / s; v*s < sh.off+sh.ln; v++ {
+				sh.vs = append(sh.vs, v)
+			}
+		} else {
+			sh.vs = []int{i}
 		}
+
+		ss.add(l.Cid, sh)
 	}
 
 	for i, l := range rnd.RecoveryLinks() {
-		i += lln
-		ss.ids[i] = l.Cid
-		ss.vects[i] = make([]byte, s)
-		ss.m[l.Cid] = &shard{
-			ss: ss,
-			i:  i,
+		ss.add(l.Cid, &shard{ss: ss, i: lln + i, vs: []int{dln + i}})
+	}
+
+	// vectors with padding only are available from the beginning
+	for v, n := range ss.miss {
+		if n == 0 {
+			ss.have(v)
 		}
 	}
 
@@ -74,8 +141,117 @@ func newShards(rnd *Node) (*shards, error) {
 	return ss, nil
 }
 
+// stripes counts data stripes of the given size needed to pack the children.
+func stripes(lens []uint64, s int) int {
+	var total uint64
+	for _, l := range lens {
+		total += l
+	}
+
+	n := int((total + uint64(s) - 1) / uint64(s))
+	if n == 0 {
+		return 1
+	}
+
+	return n
+}
+
+func (ss *shards) add(id cid.Cid, sh *shard) {
+	ss.ids[sh.i] = id
+	ss.m[id] = sh
+	for _, v := range sh.vs {
+		ss.miss[v]++
+	}
+}
+
+// ready checks whether the group has enough vectors to reconstruct the rest of them.
+func (grp *group) ready() bool {
+	return grp.hvn >= grp.rs.DataNum
+}
+
+// Recoverable reports whether all the groups can be reconstructed.
 func (ss *shards) Recoverable() bool {
-	return len(ss.hvs) >= ss.lln
+	for _, grp := range ss.grps {
+		if !grp.ready() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Satisfied reports whether all wanted shards are available or can be reconstructed.
+func (ss *shards) Satisfied() bool {
+	for _, v := range ss.vwnts {
+		if !ss.grps[ss.gs[v]].ready() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// have marks the vector as available.
+func (ss *shards) have(v int) {
+	ss.miss[v] = 0
+	ss.hvs = append(ss.hvs, v)
+	ss.grps[ss.gs[v]].hvn++
+}
+
+// reconst reconstructs wanted vectors of all the groups having enough vectors for it.
+func (ss *shards) reconst() error {
+	var done []int
+	for g, grp := range ss.grps {
+		var need []int
+		for _, v := range ss.vwnts {
+			if ss.gs[v] == g {
+				need = append(need, local(grp.vs, v))
+			}
+		}
+		if len(need) == 0 || !grp.ready() {
+			continue
+		}
+
+		has := make([]int, 0, grp.hvn)
+		for _, v := range ss.hvs {
+			if ss.gs[v] == g {
+				has = append(has, local(grp.vs, v))
+			}
+		}
+
+		vects := make([][]byte, len(grp.vs))
+		for i, v := range grp.vs {
+			vects[i] = ss.vects[v]
+		}
+
+		err := grp.rs.Reconst(vects, has, need)
+		if err != nil {
+			return err
+		}
+
+		for _, i := range need {
+			done = append(done, grp.vs[i])
+		}
+	}
+
+	for _, v := range done {
+		ss.vwnts = remove(ss.vwnts, v)
+		ss.rcs[v] = true
+		ss.have(v)
+	}
+
+	return nil
+}
+
+// local returns index of the vector within the group.
+func local(vs []int, v int) int {
+	for i, gv := range vs {
+		if gv == v {
+			return i
+		}
+	}
+
+	return -1
 }
 
 func (ss *shards) Parent() cid.Cid {
@@ -86,52 +262,84 @@ func (ss *shards) IDs() []cid.Cid {
 	return ss.ids
 }
 
+// Has checks whether the shard is already available without reconstruction.
+func (ss *shards) Has(id cid.Cid) bool {
+	sh, ok := ss.m[id]
+	return ok && sh.nd != nil
+}
+
+// Available checks whether the shard is available either from filling or reconstruction.
+// Groups are reconstructed independently, so shards of groups having enough vectors are available
+// even if other groups lack them.
+func (ss *shards) Available(id cid.Cid) bool {
+	sh, ok := ss.m[id]
+	if !ok {
+		return false
+	}
+	if sh.nd != nil {
+		return true
+	}
+
+	for _, v := range sh.vs {
+		if ss.miss[v] > 0 && !ss.grps[ss.gs[v]].ready() {
+			return false
+		}
+	}
+
+	return true
+}
+
 func (ss *shards) Want(id cid.Cid) error {
 	_, err := ss.shard(id)
 	return err
 }
 
 func (ss *shards) WantData() {
-	for i, id := range ss.ids[:ss.lln] {
-		ss.m[id].want = true
-		ss.wnts = append(ss.wnts, i)
+	for _, id := range ss.ids[:ss.lln] {
+		ss.m[id].wanted()
 	}
 }
 
 func (ss *shards) WantAll() {
 	for _, sh := range ss.m {
-		sh.want = true
-		ss.wnts = append(ss.wnts, sh.i)
+		sh.wanted()
 	}
 }
 
 func (ss *shards) Wanted() (nds []format.Node, err error) {
-	nds = make([]format.Node, len(ss.wnts))
-	for i, j := range ss.wnts {
-		nds[i], err = ss.Get(ss.ids[j])
+	wnts := append([]int(nil), ss.wnts...) // shards are removed from wanted once gotten
+	nds = make([]format.Node, 0, len(wnts))
+	for _, j := range wnts {
+		if !ss.Available(ss.ids[j]) {
+			continue // its group lacks vectors
+		}
+
+		nd, err := ss.Get(ss.ids[j])
 		if err != nil {
 			return nil, err
 		}
+
+		nds = append(nds, nd)
 	}
 
-	return
+	return nds, nil
+}
+
+// Stored returns wanted shards to store once recovery is finished.
+func (ss *shards) Stored([]format.Node) ([]format.Node, error) {
+	return ss.Wanted()
 }
 
+// Fill puts the Node into its vectors, reporting whether more Nodes are needed for the wanted ones.
 func (ss *shards) Fill(nd format.Node) bool {
 	sh, ok := ss.m[nd.Cid()]
-	if !ok || sh.nd != nil {
-		return !ss.Recoverable()
-	}
-
-	if sh.i < ss.lln {
-		n := varint.PutUvarint(ss.vects[sh.i], uint64(len(nd.RawData())))
-		copy(ss.vects[sh.i][n:], nd.RawData())
-	} else {
-		copy(ss.vects[sh.i], nd.RawData())
+	if !ok || sh.nd != nil || sh.crpt {
+		return !ss.Satisfied()
 	}
 
+	ss.put(sh, nd.RawData())
 	sh.fill(nd)
-	return !ss.Recoverable()
+	return !ss.Satisfied()
 }
 
 func (ss *shards) Get(id cid.Cid) (format.Node, error) {
@@ -142,26 +350,23 @@ func (ss *shards) Get(id cid.Cid) (format.Node, error) {
 	if sh.nd != nil {
 		return sh.nd, nil
 	}
-	if !ss.Recoverable() {
-		return nil, fmt.Errorf("reedsolomon: not enough recoverability for available Nodes")
-	}
 
-	err = ss.rs.Reconst(ss.vects, ss.hvs, ss.wnts)
+	err = ss.reconst()
 	if err != nil {
 		return nil, err
 	}
-
-	vec := ss.vects[sh.i]
-	if sh.i < ss.lln {
-		s, n, err := varint.FromUvarint(vec)
-		if err != nil {
-			return nil, err
+	for _, v := range sh.vs {
+		if ss.miss[v] > 0 {
+			return nil, fmt.Errorf("reedsolomon: not enough recoverability for available Nodes")
 		}
+	}
 
-		vec = vec[n : int(s)+n]
+	data, err := ss.verify(sh, id)
+	if err != nil {
+		return nil, err
 	}
 
-	b, _ := blocks.NewBlockWithCid(vec, id)
+	b, _ := blocks.NewBlockWithCid(data, id)
 	nd, err := format.Decode(b)
 	if err != nil {
 		return nil, err
@@ -177,22 +382,224 @@ func (ss *shards) shard(id cid.Cid) (*shard, error) {
 		return nil, fmt.Errorf("reedsolomon: wrong child")
 	}
 
-	if !sh.want {
-		sh.want = true
-		ss.wnts = append(ss.wnts, sh.i)
+	sh.wanted()
+	return sh, nil
+}
+
+// put writes the shard data to its vectors.
+func (ss *shards) put(sh *shard, data []byte) {
+	switch {
+	case sh.i >= ss.lln:
+		copy(ss.vects[sh.vs[0]], data)
+	case ss.stripe > 0:
+		for n := 0; n < len(data); {
+			pos := sh.off + n
+			n += copy(ss.vects[pos/ss.stripe][pos%ss.stripe:], data[n:])
+		}
+	default:
+		n := varint.PutUvarint(ss.vects[sh.i], uint64(len(data)))
+		copy(ss.vects[sh.i][n:], data)
 	}
+}
 
-	return sh, nil
+// take reads the shard data from the given vectors.
+func (ss *shards) take(sh *shard, vects [][]byte) ([]byte, error) {
+	switch {
+	case sh.i >= ss.lln:
+		return vects[sh.vs[0]], nil
+	case ss.stripe > 0:
+		data := make([]byte, sh.ln)
+		for n := 0; n < len(data); {
+			pos := sh.off + n
+			n += copy(data[n:], vects[pos/ss.stripe][pos%ss.stripe:])
+		}
+
+		return data, nil
+	default:
+		vec := vects[sh.i]
+		s, n, err := varint.FromUvarint(vec)
+		if err != nil {
+			return nil, err
+		}
+		if s > uint64(len(vec)-n) {
+			return nil, fmt.Errorf("reedsolomon: wrong shard length")
+		}
+
+		return vec[n : int(s)+n], nil
+	}
+}
+
+// verify takes the reconstructed shard data and checks it against the CID.
+// On mismatch, filled shards are excluded one by one until reconstruction without one of them gives the expected data.
+func (ss *shards) verify(sh *shard, id cid.Cid) ([]byte, error) {
+	data, err := ss.take(sh, ss.vects)
+	if err == nil && matches(id, data) {
+		return data, nil
+	}
+
+	for _, bid := range ss.ids {
+		bad := ss.m[bid]
+		if bad == sh || bad.nd == nil {
+			continue
+		}
+
+		data, ok := ss.exclude(sh, bad, id)
+		if ok {
+			return data, nil
+		}
+	}
+
+	return nil, fmt.Errorf("reedsolomon: reconstructed Node doesn't match %s", id)
+}
+
+// exclude reconstructs the shard again without vectors of the suspected bad shard, reporting whether it matches the CID.
+// If so, all reconstructed vectors and the ones of the bad shard are replaced with the new results.
+func (ss *shards) exclude(sh, bad *shard, id cid.Cid) ([]byte, bool) {
+	var drop []int
+	for _, v := range bad.vs {
+		if ss.miss[v] == 0 && !ss.rcs[v] {
+			drop = append(drop, v)
+		}
+	}
+	if len(drop) == 0 {
+		return nil, false
+	}
+
+	var gs []int
+	for _, v := range append(drop, sh.vs...) {
+		if !contains(gs, ss.gs[v]) {
+			gs = append(gs, ss.gs[v])
+		}
+	}
+
+	var fixed []int
+	vects := append([][]byte(nil), ss.vects...)
+	for _, g := range gs {
+		grp := ss.grps[g]
+
+		var has, need []int
+		gvects := make([][]byte, len(grp.vs))
+		for i, v := range grp.vs {
+			if ss.miss[v] == 0 && !ss.rcs[v] && !contains(drop, v) {
+				has = append(has, i)
+				gvects[i] = vects[v]
+				continue
+			}
+
+			// results are written aside, as the bad shard may still be not the one.
+			vects[v] = make([]byte, len(ss.vects[v]))
+			gvects[i] = vects[v]
+			if ss.miss[v] == 0 {
+				need = append(need, i)
+				fixed = append(fixed, v)
+			}
+		}
+
+		err := grp.rs.Reconst(gvects, has, need)
+		if err != nil {
+			return nil, false
+		}
+	}
+
+	data, err := ss.take(sh, vects)
+	if err != nil || !matches(id, data) {
+		return nil, false
+	}
+
+	for _, v := range fixed {
+		copy(ss.vects[v], vects[v])
+		ss.rcs[v] = true
+	}
+
+	// striped shards share vectors, so compare all of them with the new results to find the actually corrupted ones.
+	for _, oid := range ss.ids {
+		o := ss.m[oid]
+		if o.nd == nil || !intersects(o.vs, drop) {
+			continue
+		}
+
+		odata, err := ss.take(o, ss.vects)
+		if err == nil && bytes.Equal(odata, o.nd.RawData()) {
+			continue
+		}
+
+		// the corrupted shard is reconstructed from now on
+		log.Warnf("reedsolomon: shard %s is corrupted, excluded it from reconstruction", oid)
+		o.nd, o.crpt = nil, true
+	}
+
+	return data, true
+}
+
+// matches checks whether the data hashes to the CID.
+func matches(id cid.Cid, data []byte) bool {
+	exp, err := id.Prefix().Sum(data)
+	return err == nil && exp.Equals(id)
+}
+
+// wanted marks the shard and its missing vectors as wanted.
+func (sh *shard) wanted() {
+	if sh.want {
+		return
+	}
+	sh.want = true
+
+	if sh.nd != nil {
+		return
+	}
+	sh.ss.wnts = append(sh.ss.wnts, sh.i)
+
+	for _, v := range sh.vs {
+		if sh.ss.miss[v] > 0 && !contains(sh.ss.vwnts, v) {
+			sh.ss.vwnts = append(sh.ss.vwnts, v)
+		}
+	}
 }
 
 func (sh *shard) fill(nd format.Node) {
 	sh.nd = nd
-	sh.ss.hvs = append(sh.ss.hvs, sh.i)
+	sh.ss.wnts = remove(sh.ss.wnts, sh.i)
+
+	for _, v := range sh.vs {
+		if sh.ss.miss[v] == 0 {
+			continue // reconstructed already
+		}
 
-	for i, j := range sh.ss.wnts {
-		if j == sh.i {
-			sh.ss.wnts[i] = sh.ss.wnts[len(sh.ss.wnts)-1]
-			sh.ss.wnts = sh.ss.wnts[:len(sh.ss.wnts)-1]
+		sh.ss.miss[v]--
+		if sh.ss.miss[v] == 0 {
+			sh.ss.have(v)
+			sh.ss.vwnts = remove(sh.ss.vwnts, v)
 		}
 	}
 }
+
+func contains(s []int, e int) bool {
+	for _, v := range s {
+		if v == e {
+			return true
+		}
+	}
+
+	return false
+}
+
+func intersects(a, b []int) bool {
+	for _, v := range a {
+		if contains(b, v) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func remove(s []int, e int) []int {
+	for i, v := range s {
+		if v == e {
+			s[i] = s[len(s)-1]
+			return s[:len(s)-1]
+		}
+	}
+
+	return s
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"testing"
 
+	blocks "github.com/ipfs/go-block-format"
 	format "github.com/ipfs/go-ipld-format"
 	"github.com/ipfs/go-merkledag"
 	dstest "github.com/ipfs/go-merkledag/test"
@@ -49,3 +50,138 @@ func TestShards(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, ch2.RawData(), out2.RawData())
 }
+
+func TestShardsStriped(t *testing.T) {
+	ctx := context.Background()
+	dag := dstest.Mock()
+
+	prnt := merkledag.NodeWithData([]byte("1234567890"))
+	ch1 := merkledag.NewRawNode([]byte("03243423423423"))
+	ch2 := merkledag.NewRawNode([]byte("123450"))
+	ch3 := merkledag.NewRawNode([]byte("1234509876"))
+	ch4 := merkledag.NewRawNode([]byte("1"))
+	prnt.AddNodeLink("link", ch1)
+	prnt.AddNodeLink("link", ch2)
+	prnt.AddNodeLink("link", ch3)
+	prnt.AddNodeLink("link", ch4)
+	dag.AddMany(ctx, []format.Node{prnt, ch1, ch2, ch3, ch4})
+
+	// ch2 spans two stripes together with ch1 and ch3
+	enc, err := EncodeStriped(ctx, dag, prnt, 2, 16)
+	require.NoError(t, err)
+
+	sh, err := newShards(enc)
+	require.NoError(t, err)
+
+	sh.Fill(ch4)
+	assert.False(t, sh.Recoverable())
+	for _, l := range enc.RecoveryLinks() {
+		rnd, err := dag.Get(ctx, l.Cid)
+		require.NoError(t, err)
+		sh.Fill(rnd)
+	}
+	assert.True(t, sh.Recoverable())
+
+	for _, ch := range []format.Node{ch1, ch2, ch3} {
+		out, err := sh.Get(ch.Cid())
+		require.NoError(t, err)
+		assert.Equal(t, ch.RawData(), out.RawData())
+	}
+}
+
+// corrupt returns the Node with the same CID, but altered data.
+func corrupt(t *testing.T, nd format.Node) format.Node {
+	data := append([]byte(nil), nd.RawData()...)
+	data[len(data)-1]++
+
+	b, err := blocks.NewBlockWithCid(data, nd.Cid())
+	require.NoError(t, err)
+	out, err := merkledag.DecodeRawBlock(b)
+	require.NoError(t, err)
+	return out
+}
+
+func TestShardsCorrupted(t *testing.T) {
+	ctx := context.Background()
+	dag := dstest.Mock()
+
+	prnt := merkledag.NodeWithData([]byte("1234567890"))
+	ch1 := merkledag.NewRawNode([]byte("03243423423423"))
+	ch2 := merkledag.NewRawNode([]byte("123450"))
+	ch3 := merkledag.NewRawNode([]byte("1234509876"))
+	prnt.AddNodeLink("link", ch1)
+	prnt.AddNodeLink("link", ch2)
+	prnt.AddNodeLink("link", ch3)
+	dag.AddMany(ctx, []format.Node{prnt, ch1, ch2, ch3})
+
+	enc, err := Encode(ctx, dag, prnt, 2)
+	require.NoError(t, err)
+	rnd1, err := dag.Get(ctx, enc.RecoveryLinks()[0].Cid)
+	require.NoError(t, err)
+	rnd2, err := dag.Get(ctx, enc.RecoveryLinks()[1].Cid)
+	require.NoError(t, err)
+
+	// one more shard than needed is available, so the corrupted one can be excluded.
+	sh, err := newShards(enc)
+	require.NoError(t, err)
+	sh.Fill(ch2)
+	sh.Fill(ch3)
+	sh.Fill(corrupt(t, rnd1))
+	sh.Fill(rnd2)
+
+	out, err := sh.Get(ch1.Cid())
+	require.NoError(t, err)
+	assert.Equal(t, ch1.RawData(), out.RawData())
+
+	// the corrupted shard is reconstructed and not filled anymore.
+	sh.Fill(corrupt(t, rnd1))
+	out, err = sh.Get(rnd1.Cid())
+	require.NoError(t, err)
+	assert.Equal(t, rnd1.RawData(), out.RawData())
+
+	// no spare shards to exclude the corrupted one.
+	sh, err = newShards(enc)
+	require.NoError(t, err)
+	sh.Fill(ch2)
+	sh.Fill(corrupt(t, rnd1))
+	sh.Fill(rnd2)
+
+	_, err = sh.Get(ch1.Cid())
+	assert.Error(t, err)
+}
+
+func TestShardsStripedCorrupted(t *testing.T) {
+	ctx := context.Background()
+	dag := dstest.Mock()
+
+	prnt := merkledag.NodeWithData([]byte("1234567890"))
+	ch1 := merkledag.NewRawNode([]byte("03243423423423"))
+	ch2 := merkledag.NewRawNode([]byte("123450"))
+	ch3 := merkledag.NewRawNode([]byte("1234509876"))
+	ch4 := merkledag.NewRawNode([]byte("1"))
+	prnt.AddNodeLink("link", ch1)
+	prnt.AddNodeLink("link", ch2)
+	prnt.AddNodeLink("link", ch3)
+	prnt.AddNodeLink("link", ch4)
+	dag.AddMany(ctx, []format.Node{prnt, ch1, ch2, ch3, ch4})
+
+	enc, err := EncodeStriped(ctx, dag, prnt, 2, 16)
+	require.NoError(t, err)
+
+	sh, err := newShards(enc)
+	require.NoError(t, err)
+	sh.Fill(ch2)
+	sh.Fill(corrupt(t, ch3))
+	sh.Fill(ch4)
+	for _, l := range enc.RecoveryLinks() {
+		rnd, err := dag.Get(ctx, l.Cid)
+		require.NoError(t, err)
+		sh.Fill(rnd)
+	}
+
+	for _, ch := range []format.Node{ch1, ch3} {
+		out, err := sh.Get(ch.Cid())
+		require.NoError(t, err)
+		assert.Equal(t, ch.RawData(), out.RawData())
+	}
+}
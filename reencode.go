@@ -0,0 +1,286 @@
+package recovery
+
+import (
+	"context"
+
+	"github.com/ipfs/go-cid"
+	format "github.com/ipfs/go-ipld-format"
+)
+
+// ReencodeOption configures Reencode.
+type ReencodeOption func(*reencoder)
+
+// RemoveReplaced makes Reencode remove replaced Nodes and their Redundant Nodes no longer referenced by the new ones. Use it only if the replaced Nodes are not referenced from elsewhere, e.g. by other DAGs sharing
+// subtrees with the re-encoded one, or by KeepOriginals for unencoded Nodes now encoded.
+func RemoveReplaced() ReencodeOption {
+	return func(re *reencoder) {
+		re.remove = true
+	}
+}
+
+// ReindexParents makes Reencode update the ParentIndex, replacing indexed recovery Nodes with the new ones.
+// Recovery Nodes linking the root from outside the DAG, like anchors created with Anchor, are re-encoded
+// to link the new root with the same Recoverability.
+func ReindexParents(pi *ParentIndex) ReencodeOption {
+	return func(re *reencoder) {
+		re.pi = pi
+	}
+}
+
+// RemapOriginals makes Reencode update the CidMap populated with KeepOriginals, so original Nodes map to the new ones.
+func RemapOriginals(cm *CidMap) ReencodeOption {
+	return func(re *reencoder) {
+		re.cm = cm
+	}
+}
+
+// Reencode re-encodes the whole DAG under the root with the Encoder and Recoverability chosen by the
+// RecoverabilityFunc, e.g. to add or drop Redundant Nodes on already encoded content, and returns the new root CID.
+// Data Nodes are left untouched, while replaced Nodes are left in place, unless RemoveReplaced is given.
+// Nodes for which non-positive Recoverability is chosen are stripped to plain ProtoNodes.
+// The Encoder must write to the same DAG.
+func Reencode(ctx context.Context, dag format.DAGService, e Encoder, root cid.Cid, rf RecoverabilityFunc, opts ...ReencodeOption) (cid.Cid, error) {
+	nd, err := dag.Get(ctx, root)
+	if err != nil {
+		return cid.Undef, err
+	}
+
+	re := &reencoder{dag: dag, e: e, rf: rf, done: make(map[cid.Cid]*format.Link)}
+	for _, opt := range opts {
+		opt(re)
+	}
+
+	end, err := re.reencode(ctx, nd, 0)
+	if err != nil {
+		return cid.Undef, err
+	}
+
+	return end.Cid(), re.reanchor(ctx, nd, end)
+}
+
+type reencoder struct {
+	dag format.DAGService
+	e   Encoder
+	rf  RecoverabilityFunc
+
+	remove bool
+	pi     *ParentIndex
+	cm     *CidMap
+
+	// done maps already re-encoded Nodes to the new ones, as subtrees may be shared.
+	done map[cid.Cid]*format.Link
+}
+
+func (re *reencoder) reencode(ctx context.Context, nd format.Node, depth int) (format.Node, error) {
+	if len(nd.Links()) == 0 {
+		return nd, nil
+	}
+
+	pnd := nd
+	rn, isRecovery := nd.(Node)
+	if isRecovery {
+		pnd = Unwrap(rn)
+	}
+
+	pnd, changed, err := re.relink(ctx, pnd, depth)
+	if err != nil {
+		return nil, err
+	}
+
+	r := re.rf(pnd, depth)
+	if !changed && isRecovery && r == rn.Recoverability() {
+		return nd, nil
+	}
+	if !changed && !isRecovery && r <= 0 {
+		return nd, nil
+	}
+
+	return re.replace(ctx, nd, pnd, r)
+}
+
+// relink re-encodes all the subtrees of the Node and links the new ones to its copy.
+func (re *reencoder) relink(ctx context.Context, nd format.Node, depth int) (format.Node, bool, error) {
+	// links are updated in place, so copy them to not alter the original Node.
+	orig := nd
+	nd = nd.Copy()
+	changed := false
+	for i, l := range nd.Links() {
+		cp := *l
+		nd.Links()[i] = &cp
+
+		err := re.reencodeLink(ctx, &cp, depth+1)
+		if err != nil {
+			return nil, false, err
+		}
+
+		changed = changed || !cp.Cid.Equals(l.Cid)
+	}
+
+	nd, err := relinked(orig, nd)
+	return nd, changed, err
+}
+
+// replace encodes the unwrapped Node with the Recoverability, or stores it as is if it is non-positive,
+// and replaces the old Node with it.
+func (re *reencoder) replace(ctx context.Context, nd, pnd format.Node, r Recoverability) (format.Node, error) {
+	end := pnd
+	if r > 0 {
+		ren, err := re.e.Encode(ctx, pnd, r)
+		if err != nil {
+			return nil, err
+		}
+
+		end = ren
+	} else {
+		err := re.dag.Add(ctx, pnd)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if nd.Cid().Equals(end.Cid()) {
+		return end, nil
+	}
+
+	err := re.reindex(nd, end)
+	if err != nil {
+		return nil, err
+	}
+
+	return end, re.collect(ctx, nd, end)
+}
+
+// reindex replaces the old Node with the new one in the ParentIndex and the CidMap.
+func (re *reencoder) reindex(nd, end format.Node) error {
+	if re.pi != nil {
+		if rn, ok := nd.(Node); ok {
+			err := re.pi.Remove(rn)
+			if err != nil {
+				return err
+			}
+		}
+		if rn, ok := end.(Node); ok {
+			err := re.pi.Put(rn)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if re.cm != nil {
+		return re.cm.Replace(nd.Cid(), end.Cid())
+	}
+
+	return nil
+}
+
+// collect removes the replaced Node and its Redundant Nodes not referenced by the new one.
+func (re *reencoder) collect(ctx context.Context, nd, end format.Node) error {
+	if !re.remove {
+		return nil
+	}
+
+	keep := cid.NewSet()
+	if ren, ok := end.(Node); ok {
+		for _, l := range ren.RecoveryLinks() {
+			keep.Add(l.Cid)
+		}
+	}
+
+	rm := []cid.Cid{nd.Cid()}
+	if rn, ok := nd.(Node); ok {
+		for _, l := range rn.RecoveryLinks() {
+			if !keep.Has(l.Cid) {
+				rm = append(rm, l.Cid)
+			}
+		}
+	}
+
+	return re.dag.RemoveMany(ctx, rm)
+}
+
+// reanchor re-encodes recovery Nodes indexed as parents of the replaced root, so they protect the new one.
+func (re *reencoder) reanchor(ctx context.Context, nd, end format.Node) error {
+	if re.pi == nil || nd.Cid().Equals(end.Cid()) {
+		return nil
+	}
+
+	ids, err := re.pi.Get(nd.Cid())
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		and, err := re.dag.Get(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		an, ok := and.(Node)
+		if !ok {
+			continue
+		}
+
+		// links to other replaced Nodes, e.g. root children, are updated as well.
+		orig := Unwrap(an)
+		pnd := orig.Copy()
+		for i, l := range pnd.Links() {
+			cp := *l
+			if nl, ok := re.done[l.Cid]; ok {
+				cp.Cid, cp.Size = nl.Cid, nl.Size
+			}
+			if l.Cid.Equals(nd.Cid()) {
+				cp.Size, err = end.Size()
+				if err != nil {
+					return err
+				}
+
+				cp.Cid = end.Cid()
+			}
+
+			pnd.Links()[i] = &cp
+		}
+
+		pnd, err = relinked(orig, pnd)
+		if err != nil {
+			return err
+		}
+
+		_, err = re.replace(ctx, an, pnd, an.Recoverability())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// reencodeLink re-encodes the subtree under the link and updates the link to point to the new one.
+func (re *reencoder) reencodeLink(ctx context.Context, l *format.Link, depth int) error {
+	if nl, ok := re.done[l.Cid]; ok {
+		l.Cid, l.Size = nl.Cid, nl.Size
+		return nil
+	}
+
+	nd, err := l.GetNode(ctx, re.dag)
+	if err != nil {
+		return err
+	}
+
+	end, err := re.reencode(ctx, nd, depth)
+	if err != nil {
+		return err
+	}
+
+	if !nd.Cid().Equals(end.Cid()) {
+		l.Size, err = end.Size()
+		if err != nil {
+			return err
+		}
+
+		l.Cid = end.Cid()
+	}
+
+	re.done[nd.Cid()] = &format.Link{Cid: l.Cid, Size: l.Size}
+	return nil
+}
@@ -0,0 +1,161 @@
+package recovery_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ipfs/go-blockservice"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	dsync "github.com/ipfs/go-datastore/sync"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	offline "github.com/ipfs/go-ipfs-exchange-offline"
+	format "github.com/ipfs/go-ipld-format"
+	"github.com/ipfs/go-merkledag"
+	dstest "github.com/ipfs/go-merkledag/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	recovery "github.com/Wondertan/go-ipfs-recovery"
+	"github.com/Wondertan/go-ipfs-recovery/reedsolomon"
+)
+
+func TestReencode(t *testing.T) {
+	ctx := context.Background()
+	dag := dstest.Mock()
+	e := reedsolomon.NewEncoder(dag)
+
+	in, err := recovery.EncodeDAG(ctx, dag, e, newTestDAG(t, dag, 3), 1)
+	require.NoError(t, err)
+
+	var oldParity []*format.Link
+	for _, id := range append(cids(in.Links()), in.Cid()) {
+		oldParity = append(oldParity, mustGet(t, dag, id).(recovery.Node).RecoveryLinks()...)
+	}
+
+	// add parity
+	id, err := recovery.Reencode(ctx, dag, e, in.Cid(), recovery.ByDepth(3, 2), recovery.RemoveReplaced())
+	require.NoError(t, err)
+
+	out := mustGet(t, dag, id).(recovery.Node)
+	assert.Equal(t, 3, out.Recoverability())
+	parity := cid.NewSet()
+	for _, l := range out.RecoveryLinks() {
+		parity.Add(l.Cid)
+	}
+	for _, l := range out.Links() {
+		ch := mustGet(t, dag, l.Cid).(recovery.Node)
+		assert.Equal(t, 2, ch.Recoverability())
+		for _, pl := range ch.RecoveryLinks() {
+			parity.Add(pl.Cid)
+		}
+
+		for _, gl := range ch.Links() {
+			mustGet(t, dag, gl.Cid) // data is untouched
+		}
+	}
+
+	_, err = dag.Get(ctx, in.Cid())
+	assert.Equal(t, format.ErrNotFound, err)
+	for _, l := range oldParity {
+		if !parity.Has(l.Cid) {
+			_, err = dag.Get(ctx, l.Cid)
+			assert.Equal(t, format.ErrNotFound, err)
+		}
+	}
+
+	// drop parity
+	id, err = recovery.Reencode(ctx, dag, e, id, recovery.ByDepth(0, 1), recovery.RemoveReplaced())
+	require.NoError(t, err)
+
+	nd := mustGet(t, dag, id)
+	_, ok := nd.(recovery.Node)
+	assert.False(t, ok)
+	for _, l := range nd.Links() {
+		assert.Equal(t, 1, mustGet(t, dag, l.Cid).(recovery.Node).Recoverability())
+	}
+	for _, l := range out.RecoveryLinks() {
+		_, err = dag.Get(ctx, l.Cid)
+		assert.Equal(t, format.ErrNotFound, err)
+	}
+
+	// nothing to change
+	same, err := recovery.Reencode(ctx, dag, e, id, recovery.ByDepth(0, 1))
+	require.NoError(t, err)
+	assert.True(t, id.Equals(same))
+}
+
+func TestReencodeShared(t *testing.T) {
+	ctx := context.Background()
+	dag := dstest.Mock()
+	e := reedsolomon.NewEncoder(dag)
+
+	in, err := recovery.EncodeDAG(ctx, dag, e, newTestDAG(t, dag, 3), 1)
+	require.NoError(t, err)
+
+	// another DAG links the same encoded subtree
+	shared := mustGet(t, dag, in.Links()[0].Cid).(recovery.Node)
+	other := merkledag.NodeWithData([]byte("other"))
+	require.NoError(t, other.AddNodeLink("", shared))
+	require.NoError(t, dag.Add(ctx, other))
+
+	_, err = recovery.Reencode(ctx, dag, e, in.Cid(), recovery.ByDepth(2))
+	require.NoError(t, err)
+
+	mustGet(t, dag, shared.Cid())
+	for _, l := range shared.RecoveryLinks() {
+		mustGet(t, dag, l.Cid)
+	}
+}
+
+func TestReencodeReindex(t *testing.T) {
+	ctx := context.Background()
+
+	ds := dsync.MutexWrap(datastore.NewMapDatastore())
+	bstore := blockstore.NewBlockstore(ds)
+	ex := offline.Exchange(bstore)
+	pi, cm := recovery.NewParentIndex(ds), recovery.NewCidMap(ds)
+	dag := recovery.NewIndexedDAG(merkledag.NewDAGService(blockservice.New(bstore, ex)), pi)
+	e := reedsolomon.NewEncoder(dag)
+
+	nd := newTestDAG(t, dag, 3)
+	in, err := recovery.EncodeDAG(ctx, dag, e, nd, 1, recovery.KeepOriginals(cm), recovery.Anchor(1, pi))
+	require.NoError(t, err)
+
+	id, err := recovery.Reencode(ctx, dag, e, in.Cid(), recovery.ByDepth(2),
+		recovery.ReindexParents(pi), recovery.RemapOriginals(cm), recovery.RemoveReplaced())
+	require.NoError(t, err)
+	out := mustGet(t, dag, id)
+
+	// originals are kept and map to the new Nodes
+	end, ok, err := cm.Get(nd.Cid())
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.True(t, id.Equals(end))
+	for i, l := range nd.Links() {
+		mustGet(t, dag, l.Cid)
+
+		end, ok, err := cm.Get(l.Cid)
+		require.NoError(t, err)
+		require.True(t, ok)
+		assert.True(t, out.Links()[i].Cid.Equals(end))
+	}
+
+	// only the new Nodes are indexed
+	ch := out.Links()[0].Cid
+	ids, err := pi.Get(mustGet(t, dag, ch).Links()[0].Cid)
+	require.NoError(t, err)
+	assert.Equal(t, []cid.Cid{ch}, ids)
+
+	// and the root is anchored again
+	ids, err = pi.Get(id)
+	require.NoError(t, err)
+	require.Len(t, ids, 1)
+	an := mustGet(t, dag, ids[0]).(recovery.Node)
+	assert.Equal(t, 1, an.Recoverability())
+	assert.True(t, an.Links()[len(an.Links())-1].Cid.Equals(id))
+
+	ids, err = pi.Get(in.Cid())
+	require.NoError(t, err)
+	assert.Empty(t, ids)
+}
@@ -0,0 +1,80 @@
+package recovery
+
+import (
+	"fmt"
+
+	blocks "github.com/ipfs/go-block-format"
+	"github.com/ipfs/go-cid"
+	cbor "github.com/ipfs/go-ipld-cbor"
+	format "github.com/ipfs/go-ipld-format"
+	"github.com/ipfs/go-merkledag"
+)
+
+// relinked returns the copy of the original Node with links updated in place as a Node actually linking them.
+// ProtoNodes are built from their links, while dag-cbor Nodes are re-encoded with the new CIDs substituted for the old
+// ones, keeping the original CID prefix. Nodes of other codecs can't be relinked.
+func relinked(orig, nd format.Node) (format.Node, error) {
+	ids := make(map[cid.Cid]cid.Cid)
+	for i, l := range orig.Links() {
+		if nl := nd.Links()[i]; !nl.Cid.Equals(l.Cid) {
+			ids[l.Cid] = nl.Cid
+		}
+	}
+	if len(ids) == 0 {
+		return nd, nil
+	}
+
+	if _, ok := nd.(*merkledag.ProtoNode); ok {
+		return nd, nil
+	}
+	if orig.Cid().Type() != cid.DagCBOR {
+		return nil, fmt.Errorf("recovery: can't relink Node of codec %d", orig.Cid().Type())
+	}
+
+	var obj interface{}
+	err := cbor.DecodeInto(orig.RawData(), &obj)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := cbor.DumpObject(substitute(obj, ids))
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := orig.Cid().Prefix().Sum(data)
+	if err != nil {
+		return nil, err
+	}
+
+	blk, err := blocks.NewBlockWithCid(data, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return cbor.DecodeBlock(blk)
+}
+
+// substitute replaces CIDs within the decoded dag-cbor object.
+func substitute(obj interface{}, ids map[cid.Cid]cid.Cid) interface{} {
+	switch obj := obj.(type) {
+	case cid.Cid:
+		if id, ok := ids[obj]; ok {
+			return id
+		}
+	case map[string]interface{}:
+		for k, v := range obj {
+			obj[k] = substitute(v, ids)
+		}
+	case map[interface{}]interface{}:
+		for k, v := range obj {
+			obj[k] = substitute(v, ids)
+		}
+	case []interface{}:
+		for i, v := range obj {
+			obj[i] = substitute(v, ids)
+		}
+	}
+
+	return obj
+}
@@ -0,0 +1,179 @@
+package recovery
+
+import (
+	"context"
+
+	"github.com/ipfs/go-cid"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	format "github.com/ipfs/go-ipld-format"
+)
+
+// RepairReport describes the outcome of the DAG repair.
+type RepairReport struct {
+	// Restored lists reconstructed Data Nodes.
+	Restored []cid.Cid
+	// Regenerated lists reconstructed Redundant Nodes.
+	Regenerated []cid.Cid
+	// Unrecoverable lists missing Nodes, which can't be reconstructed.
+	Unrecoverable []cid.Cid
+}
+
+// Healthy reports whether nothing is lost in the DAG.
+func (rr *RepairReport) Healthy() bool {
+	return len(rr.Unrecoverable) == 0
+}
+
+// Repair walks the whole DAG under the root available in the Blockstore, reconstructs all missing Data and Redundant
+// Nodes with the Recoverer and writes them to the DAG. Like Audit, it finds missing Nodes locally, so it does not wait
+// for the network to give up on them. Subtrees of unrecoverable Nodes are not walked.
+func Repair(ctx context.Context, bs blockstore.Blockstore, dag format.DAGService, r Recoverer, root cid.Cid) (*RepairReport, error) {
+	rp := &repairer{bs: bs, dag: dag, r: r, rr: &RepairReport{}, seen: cid.NewSet()}
+	nd, ok, err := rp.get(root)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, format.ErrNotFound
+	}
+
+	return rp.rr, rp.repair(ctx, nd)
+}
+
+type repairer struct {
+	bs   blockstore.Blockstore
+	dag  format.DAGService
+	r    Recoverer
+	rr   *RepairReport
+	seen *cid.Set
+}
+
+// get returns the Node if it is available locally.
+func (rp *repairer) get(id cid.Cid) (format.Node, bool, error) {
+	b, err := rp.bs.Get(id)
+	switch err {
+	case nil:
+	case blockstore.ErrNotFound:
+		return nil, false, nil
+	default:
+		return nil, false, err
+	}
+
+	nd, err := format.Decode(b)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return nd, true, nil
+}
+
+func (rp *repairer) repair(ctx context.Context, nd format.Node) error {
+	if !rp.seen.Visit(nd.Cid()) {
+		return nil
+	}
+
+	rn, isRecovery := nd.(Node)
+	ids := make([]cid.Cid, 0, len(nd.Links()))
+	for _, l := range nd.Links() {
+		ids = append(ids, l.Cid)
+	}
+	if isRecovery {
+		for _, l := range rn.RecoveryLinks() {
+			ids = append(ids, l.Cid)
+		}
+	}
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	var missing []cid.Cid
+	nds := make(map[cid.Cid]format.Node, len(ids))
+	for _, id := range ids {
+		nd, ok, err := rp.get(id)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			missing = append(missing, id)
+			continue
+		}
+
+		nds[id] = nd
+	}
+
+	if len(missing) > 0 {
+		if !isRecovery {
+			rp.rr.Unrecoverable = append(rp.rr.Unrecoverable, missing...)
+		} else {
+			err := rp.recover(ctx, rn, missing, nds)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, l := range nd.Links() {
+		ch, ok := nds[l.Cid]
+		if !ok {
+			continue
+		}
+
+		err := rp.repair(ctx, ch)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// recover reconstructs missing Nodes of the recovery Node, stores them and puts them into nds.
+func (rp *repairer) recover(ctx context.Context, rn Node, missing []cid.Cid, nds map[cid.Cid]format.Node) error {
+	out, err := rp.r.Recover(ctx, rn, missing...)
+	if err != nil {
+		log.Warnf("Repair of %s failed: %s", rn.Cid(), err)
+		rp.rr.Unrecoverable = append(rp.rr.Unrecoverable, missing...)
+		return nil
+	}
+
+	for range missing {
+		var no *format.NodeOption
+		select {
+		case no = <-out:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if no == nil {
+			break // the Recoverer has nothing more to say
+		}
+		if no.Err != nil {
+			continue
+		}
+
+		err = rp.dag.Add(ctx, no.Node)
+		if err != nil {
+			return err
+		}
+
+		nds[no.Node.Cid()] = no.Node
+	}
+
+	data := cid.NewSet()
+	for _, l := range rn.Links() {
+		data.Add(l.Cid)
+	}
+
+	for _, id := range missing {
+		_, ok := nds[id]
+		switch {
+		case !ok:
+			rp.rr.Unrecoverable = append(rp.rr.Unrecoverable, id)
+		case data.Has(id):
+			rp.rr.Restored = append(rp.rr.Restored, id)
+		default:
+			rp.rr.Regenerated = append(rp.rr.Regenerated, id)
+		}
+	}
+
+	return nil
+}
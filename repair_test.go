@@ -0,0 +1,184 @@
+package recovery_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ipfs/go-blockservice"
+	"github.com/ipfs/go-cid"
+	"github.com/ipfs/go-datastore"
+	dsync "github.com/ipfs/go-datastore/sync"
+	blockstore "github.com/ipfs/go-ipfs-blockstore"
+	offline "github.com/ipfs/go-ipfs-exchange-offline"
+	format "github.com/ipfs/go-ipld-format"
+	"github.com/ipfs/go-merkledag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	recovery "github.com/Wondertan/go-ipfs-recovery"
+	"github.com/Wondertan/go-ipfs-recovery/entanglement"
+	"github.com/Wondertan/go-ipfs-recovery/reedsolomon"
+)
+
+// newTestDAG creates two level DAG with the given amount of children on each level.
+func newTestDAG(t *testing.T, dag format.DAGService, n int) format.Node {
+	ctx := context.Background()
+	root := merkledag.NodeWithData([]byte("root"))
+	for i := 0; i < n; i++ {
+		ch := merkledag.NodeWithData([]byte{byte(i)})
+		for j := 0; j < n; j++ {
+			gch := merkledag.NewRawNode([]byte{byte(i), byte(j)})
+			require.NoError(t, dag.Add(ctx, gch))
+			require.NoError(t, ch.AddNodeLink("", gch))
+		}
+
+		require.NoError(t, dag.Add(ctx, ch))
+		require.NoError(t, root.AddNodeLink("", ch))
+	}
+
+	require.NoError(t, dag.Add(ctx, root))
+	return root
+}
+
+// newTestStore creates the DAG on top of the Blockstore, so Nodes missing locally are known.
+func newTestStore() (blockstore.Blockstore, format.DAGService) {
+	bs := blockstore.NewBlockstore(dsync.MutexWrap(datastore.NewMapDatastore()))
+	return bs, merkledag.NewDAGService(blockservice.New(bs, offline.Exchange(bs)))
+}
+
+func TestRepair(t *testing.T) {
+	ctx := context.Background()
+	bs, dag := newTestStore()
+
+	enc, err := recovery.EncodeDAG(ctx, dag, reedsolomon.NewEncoder(dag), newTestDAG(t, dag, 3), 2)
+	require.NoError(t, err)
+	root := enc.(recovery.Node)
+
+	ch, err := dag.Get(ctx, root.Links()[1].Cid)
+	require.NoError(t, err)
+
+	lostData := []cid.Cid{root.Links()[0].Cid, ch.Links()[2].Cid}
+	lostParity := []cid.Cid{root.RecoveryLinks()[0].Cid, ch.(recovery.Node).RecoveryLinks()[1].Cid}
+	for _, id := range append(lostData, lostParity...) {
+		require.NoError(t, dag.Remove(ctx, id))
+	}
+
+	rr, err := recovery.Repair(ctx, bs, dag, reedsolomon.NewRecoverer(ctx, dag, recovery.Requested), root.Cid())
+	require.NoError(t, err)
+	assert.True(t, rr.Healthy())
+	assert.ElementsMatch(t, lostData, rr.Restored)
+	assert.ElementsMatch(t, lostParity, rr.Regenerated)
+
+	for _, id := range append(lostData, lostParity...) {
+		_, err := dag.Get(ctx, id)
+		assert.NoError(t, err)
+	}
+
+	rr, err = recovery.Repair(ctx, bs, dag, reedsolomon.NewRecoverer(ctx, dag, recovery.Requested), root.Cid())
+	require.NoError(t, err)
+	assert.Equal(t, &recovery.RepairReport{}, rr)
+}
+
+func TestRepairUnrecoverable(t *testing.T) {
+	ctx := context.Background()
+	bs, dag := newTestStore()
+
+	enc, err := recovery.EncodeDAG(ctx, dag, reedsolomon.NewEncoder(dag), newTestDAG(t, dag, 3), 1)
+	require.NoError(t, err)
+	root := enc.(recovery.Node)
+
+	lost := []cid.Cid{root.Links()[0].Cid, root.Links()[1].Cid}
+	for _, id := range lost {
+		require.NoError(t, dag.Remove(ctx, id))
+	}
+
+	rr, err := recovery.Repair(ctx, bs, dag, reedsolomon.NewRecoverer(ctx, dag, recovery.Requested), root.Cid())
+	require.NoError(t, err)
+	assert.False(t, rr.Healthy())
+	assert.ElementsMatch(t, lost, rr.Unrecoverable)
+}
+
+func TestRepairMux(t *testing.T) {
+	ctx := context.Background()
+	bs, dag := newTestStore()
+
+	root := newTestDAG(t, dag, 2)
+	ch1, err := entanglement.Encode(ctx, dag, mustGet(t, dag, root.Links()[0].Cid), 2, 1, 1)
+	require.NoError(t, err)
+	ch2, err := reedsolomon.Encode(ctx, dag, mustGet(t, dag, root.Links()[1].Cid), 1)
+	require.NoError(t, err)
+
+	pnd := merkledag.NodeWithData([]byte("root"))
+	require.NoError(t, pnd.AddNodeLink("", ch1))
+	require.NoError(t, pnd.AddNodeLink("", ch2))
+	require.NoError(t, dag.Add(ctx, pnd))
+
+	lost := []cid.Cid{ch1.Links()[0].Cid, ch2.Links()[1].Cid}
+	for _, id := range lost {
+		require.NoError(t, dag.Remove(ctx, id))
+	}
+
+	rr, err := recovery.Repair(ctx, bs, dag, recovery.Recoverers{
+		entanglement.Codec: entanglement.NewRecoverer(ctx, dag, recovery.Requested),
+		reedsolomon.Codec:  reedsolomon.NewRecoverer(ctx, dag, recovery.Requested),
+	}, pnd.Cid())
+	require.NoError(t, err)
+	assert.True(t, rr.Healthy())
+	assert.ElementsMatch(t, lost, rr.Restored)
+}
+
+// onlineDAG waits for missing Nodes until the context is done, like the network does.
+type onlineDAG struct {
+	format.DAGService
+}
+
+func (d *onlineDAG) Get(ctx context.Context, id cid.Cid) (format.Node, error) {
+	nd, err := d.DAGService.Get(ctx, id)
+	if err == format.ErrNotFound {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	return nd, err
+}
+
+func (d *onlineDAG) GetMany(ctx context.Context, ids []cid.Cid) <-chan *format.NodeOption {
+	out := make(chan *format.NodeOption)
+	go func() {
+		defer close(out)
+		for no := range d.DAGService.GetMany(ctx, ids) {
+			select {
+			case out <- no:
+			case <-ctx.Done():
+				return
+			}
+		}
+		<-ctx.Done()
+	}()
+	return out
+}
+
+func TestRepairOnline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	bs, dag := newTestStore()
+
+	enc, err := recovery.EncodeDAG(ctx, dag, reedsolomon.NewEncoder(dag), newTestDAG(t, dag, 3), 1)
+	require.NoError(t, err)
+	root := enc.(recovery.Node)
+	require.NoError(t, dag.Remove(ctx, root.Links()[0].Cid))
+
+	// missing Nodes are found locally, not waiting for the network
+	rr, err := recovery.Repair(ctx, bs, &onlineDAG{dag}, reedsolomon.NewRecoverer(ctx, dag, recovery.Requested), root.Cid())
+	require.NoError(t, err)
+	assert.True(t, rr.Healthy())
+	assert.Equal(t, []cid.Cid{root.Links()[0].Cid}, rr.Restored)
+	assert.NoError(t, ctx.Err())
+}
+
+func mustGet(t *testing.T, dag format.DAGService, id cid.Cid) format.Node {
+	nd, err := dag.Get(context.Background(), id)
+	require.NoError(t, err)
+	return nd
+}
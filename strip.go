@@ -0,0 +1,31 @@
+package recovery
+
+import (
+	"context"
+
+	"github.com/ipfs/go-cid"
+	format "github.com/ipfs/go-ipld-format"
+)
+
+// StripDAG rewrites every recovery Node in the DAG under the root back to the Node it wraps, fixing up the links,
+// and returns the CID of the original root, so the content can be shared with peers not aware of recovery.
+// The encoded DAG is left intact, use Reencode with non-positive Recoverability and
+// RemoveReplaced to drop it instead.
+func StripDAG(ctx context.Context, dag format.DAGService, root cid.Cid) (cid.Cid, error) {
+	nd, err := dag.Get(ctx, root)
+	if err != nil {
+		return cid.Undef, err
+	}
+
+	re := &reencoder{
+		dag:  dag,
+		rf:   func(format.Node, int) Recoverability { return 0 },
+		done: make(map[cid.Cid]*format.Link),
+	}
+	end, err := re.reencode(ctx, nd, 0)
+	if err != nil {
+		return cid.Undef, err
+	}
+
+	return end.Cid(), nil
+}
@@ -0,0 +1,74 @@
+package recovery_test
+
+import (
+	"context"
+	"testing"
+
+	format "github.com/ipfs/go-ipld-format"
+	"github.com/ipfs/go-merkledag"
+	dstest "github.com/ipfs/go-merkledag/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	recovery "github.com/Wondertan/go-ipfs-recovery"
+	"github.com/Wondertan/go-ipfs-recovery/entanglement"
+	"github.com/Wondertan/go-ipfs-recovery/reedsolomon"
+)
+
+func TestStripDAG(t *testing.T) {
+	ctx := context.Background()
+	dag := dstest.Mock()
+
+	nd := newTestDAG(t, dag, 3)
+	orig := cids(nd.Links())
+
+	enc, err := recovery.EncodeDAG(ctx, dag, reedsolomon.NewEncoder(dag), nd, 2)
+	require.NoError(t, err)
+	require.NoError(t, dag.RemoveMany(ctx, append(orig, nd.Cid())))
+
+	id, err := recovery.StripDAG(ctx, dag, enc.Cid())
+	require.NoError(t, err)
+	assert.True(t, nd.Cid().Equals(id))
+
+	out := mustGet(t, dag, id)
+	assert.Equal(t, nd.RawData(), out.RawData())
+	for _, id := range orig {
+		_, ok := mustGet(t, dag, id).(recovery.Node)
+		assert.False(t, ok)
+	}
+
+	// encoded DAG is still there
+	mustGet(t, dag, enc.Cid())
+}
+
+func TestStripDAGCidV1(t *testing.T) {
+	ctx := context.Background()
+	dag := dstest.Mock()
+
+	for name, e := range map[string]recovery.Encoder{
+		"reedsolomon":  reedsolomon.NewEncoder(dag),
+		"entanglement": entanglement.NewEncoder(dag, 1, 1),
+	} {
+		t.Run(name, func(t *testing.T) {
+			root := merkledag.NodeWithData([]byte(name))
+			root.SetCidBuilder(merkledag.V1CidPrefix())
+			for i := 0; i < 3; i++ {
+				ch := merkledag.NodeWithData([]byte{byte(i)})
+				ch.SetCidBuilder(merkledag.V1CidPrefix())
+				require.NoError(t, ch.AddNodeLink("", merkledag.NewRawNode([]byte{byte(i), 0})))
+				require.NoError(t, dag.AddMany(ctx, []format.Node{ch, merkledag.NewRawNode([]byte{byte(i), 0})}))
+				require.NoError(t, root.AddNodeLink("", ch))
+			}
+			require.NoError(t, dag.Add(ctx, root))
+
+			enc, err := recovery.EncodeDAG(ctx, dag, e, root, 1)
+			require.NoError(t, err)
+
+			// recovery Nodes are decoded from the store, not taken from the encoding
+			id, err := recovery.StripDAG(ctx, dag, enc.Cid())
+			require.NoError(t, err)
+			assert.True(t, root.Cid().Equals(id))
+			assert.EqualValues(t, 1, id.Version())
+		})
+	}
+}